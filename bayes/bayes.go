@@ -0,0 +1,231 @@
+package bayes
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/luizbranco/waukeen"
+)
+
+const (
+	// suggestions below minConfidence are noise, not worth showing
+	minConfidence  = 0.05
+	maxSuggestions = 3
+)
+
+// amount buckets in cents, a transaction falls in the first one above it
+var buckets = []int64{1000, 2500, 5000, 10000, 25000, 50000, 100000}
+
+// Classifier suggests tags with a naive Bayes model of the words in the
+// title and description and the amount of tagged transactions. Rules run
+// through the wrapped transformer.
+type Classifier struct {
+	Rules waukeen.TransactionTransformer
+	// Threshold is the confidence from which suggestions are applied, zero
+	// never applies them. Tags applied this way are trained on like the
+	// ones given by hand or by rules, the model then reinforces itself.
+	Threshold float64
+
+	mu    sync.RWMutex
+	model *model
+}
+
+// model keeps the totals Suggest needs next to the counts.
+type model struct {
+	*waukeen.TagModel
+	transactions int
+	tokens       map[string]int
+	vocabulary   map[string]bool
+}
+
+func (c *Classifier) Transform(t *waukeen.Transaction, r waukeen.Rule) bool {
+	return c.Rules.Transform(t, r)
+}
+
+func (c *Classifier) Train(trs []waukeen.Transaction) *waukeen.TagModel {
+	m := &waukeen.TagModel{
+		Tags:   make(map[string]int),
+		Tokens: make(map[string]map[string]int),
+	}
+
+	for i := range trs {
+		t := &trs[i]
+		if len(t.Tags) == 0 {
+			continue
+		}
+
+		tokens := Tokens(t)
+		for _, tag := range t.Tags {
+			m.Tags[tag]++
+			counts := m.Tokens[tag]
+			if counts == nil {
+				counts = make(map[string]int)
+				m.Tokens[tag] = counts
+			}
+			for _, tk := range tokens {
+				counts[tk]++
+			}
+		}
+	}
+
+	return m
+}
+
+func (c *Classifier) Load(tm *waukeen.TagModel) {
+	var m *model
+
+	if tm != nil && len(tm.Tags) > 0 {
+		m = &model{
+			TagModel:   tm,
+			tokens:     make(map[string]int),
+			vocabulary: make(map[string]bool),
+		}
+		for tag, n := range tm.Tags {
+			m.transactions += n
+			for tk, count := range tm.Tokens[tag] {
+				m.tokens[tag] += count
+				m.vocabulary[tk] = true
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.model = m
+	c.mu.Unlock()
+}
+
+// Suggest scores every tag learned and normalizes the scores into
+// confidences. Tokens never seen in training are ignored, a transaction
+// without any known word gets no suggestion whatever its amount.
+func (c *Classifier) Suggest(t *waukeen.Transaction) []waukeen.TagSuggestion {
+	c.mu.RLock()
+	m := c.model
+	c.mu.RUnlock()
+
+	if m == nil {
+		return nil
+	}
+
+	var known []string
+	for _, w := range words(t) {
+		if m.vocabulary[w] {
+			known = append(known, w)
+		}
+	}
+	if len(known) == 0 {
+		return nil
+	}
+	if tk := amountToken(t.Amount); m.vocabulary[tk] {
+		known = append(known, tk)
+	}
+
+	tags := make([]string, 0, len(m.Tags))
+	scores := make([]float64, 0, len(m.Tags))
+	max := math.Inf(-1)
+	v := float64(len(m.vocabulary))
+
+	for tag, n := range m.Tags {
+		score := math.Log(float64(n) / float64(m.transactions))
+		total := float64(m.tokens[tag])
+		for _, tk := range known {
+			score += math.Log((float64(m.Tokens[tag][tk]) + 1) / (total + v))
+		}
+		tags = append(tags, tag)
+		scores = append(scores, score)
+		if score > max {
+			max = score
+		}
+	}
+
+	var sum float64
+	for i, s := range scores {
+		scores[i] = math.Exp(s - max)
+		sum += scores[i]
+	}
+
+	var suggestions []waukeen.TagSuggestion
+
+	for i, tag := range tags {
+		confidence := scores[i] / sum
+		if confidence < minConfidence || hasTag(t, tag) {
+			continue
+		}
+		suggestions = append(suggestions, waukeen.TagSuggestion{
+			Tag:        tag,
+			Confidence: confidence,
+			Confident:  c.Threshold > 0 && confidence >= c.Threshold,
+		})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		return a.Tag < b.Tag
+	})
+
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+
+	return suggestions
+}
+
+// Tokens are the words of the transaction plus its amount bucket.
+func Tokens(t *waukeen.Transaction) []string {
+	return append(words(t), amountToken(t.Amount))
+}
+
+// words are the distinct lower case words of the title and description,
+// leaving out numbers and single letters.
+func words(t *waukeen.Transaction) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+
+	text := strings.ToLower(t.Title + " " + t.Description)
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, w := range words {
+		if len(w) < 2 || seen[w] || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		seen[w] = true
+		tokens = append(tokens, w)
+	}
+
+	return tokens
+}
+
+func amountToken(amount int64) string {
+	sign := "out"
+	if amount > 0 {
+		sign = "in"
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+
+	for _, b := range buckets {
+		if amount < b {
+			return "amount:" + sign + ":" + strconv.FormatInt(b/100, 10)
+		}
+	}
+
+	return "amount:" + sign + ":more"
+}
+
+func hasTag(t *waukeen.Transaction, tag string) bool {
+	for _, name := range t.Tags {
+		if name == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package bayes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/transformer"
+)
+
+func TestTagClassifierInterface(t *testing.T) {
+	var _ waukeen.TagClassifier = &Classifier{}
+}
+
+func TestTokens(t *testing.T) {
+	tr := &waukeen.Transaction{
+		Title:       "DOMINOS #1234 Toronto",
+		Description: "Pizza - toronto, ON",
+		Amount:      -2350,
+	}
+
+	want := []string{"dominos", "toronto", "pizza", "on", "amount:out:25"}
+	got := Tokens(tr)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants %v, got %v", want, got)
+	}
+
+	tr = &waukeen.Transaction{Title: "PAYROLL", Amount: 250000}
+	want = []string{"payroll", "amount:in:more"}
+	got = Tokens(tr)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants %v, got %v", want, got)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	trs := []waukeen.Transaction{
+		{Title: "DOMINOS PIZZA", Amount: -2300, Tags: []string{"restaurants"}},
+		{Title: "PIZZA PIZZA", Amount: -1800, Tags: []string{"restaurants"}},
+		{Title: "SUBWAY", Amount: -1200, Tags: []string{"restaurants"}},
+		{Title: "LOBLAWS", Amount: -8500, Tags: []string{"groceries"}},
+		{Title: "NO FRILLS", Amount: -6400, Tags: []string{"groceries"}},
+		{Title: "UBER TRIP", Amount: -1500, Tags: []string{"transportation"}},
+		{Title: "UNTAGGED", Amount: -100},
+	}
+
+	c := &Classifier{Rules: transformer.Text{}, Threshold: 0.7}
+
+	model := c.Train(trs)
+
+	wantTags := map[string]int{"restaurants": 3, "groceries": 2, "transportation": 1}
+	if !reflect.DeepEqual(wantTags, model.Tags) {
+		t.Errorf("wants %v, got %v", wantTags, model.Tags)
+	}
+	if n := model.Tokens["restaurants"]["pizza"]; n != 2 {
+		t.Errorf("wants pizza counted twice, got %d", n)
+	}
+	if _, ok := model.Tokens["untagged"]; ok {
+		t.Errorf("wants untagged transactions left out, got %v", model.Tokens)
+	}
+
+	t.Run("Not loaded", func(t *testing.T) {
+		got := c.Suggest(&waukeen.Transaction{Title: "PIZZA"})
+		if got != nil {
+			t.Errorf("wants no suggestions, got %+v", got)
+		}
+	})
+
+	c.Load(model)
+
+	t.Run("Unknown tokens", func(t *testing.T) {
+		got := c.Suggest(&waukeen.Transaction{Title: "SOMETHING ELSE", Amount: 999999})
+		if got != nil {
+			t.Errorf("wants no suggestions, got %+v", got)
+		}
+	})
+
+	t.Run("Known amount only", func(t *testing.T) {
+		got := c.Suggest(&waukeen.Transaction{Title: "SOMETHING ELSE", Amount: -2100})
+		if got != nil {
+			t.Errorf("wants no suggestions, got %+v", got)
+		}
+
+		tr := &waukeen.Transaction{Title: "NEW MERCHANT", Amount: -1900}
+		waukeen.ApplyRules(tr, nil, c)
+		if len(tr.Tags) > 0 {
+			t.Errorf("wants unknown merchant left untagged, got %v", tr.Tags)
+		}
+	})
+
+	t.Run("Confident", func(t *testing.T) {
+		got := c.Suggest(&waukeen.Transaction{Title: "DOMINOS PIZZA DELIVERY", Amount: -2100})
+		if len(got) == 0 || got[0].Tag != "restaurants" || !got[0].Confident {
+			t.Fatalf("wants confident restaurants suggestion, got %+v", got)
+		}
+		for i := 1; i < len(got); i++ {
+			if got[i].Confident || got[i].Confidence > got[i-1].Confidence {
+				t.Errorf("wants descending unconfident suggestions, got %+v", got)
+			}
+		}
+	})
+
+	t.Run("Existing tags", func(t *testing.T) {
+		tr := &waukeen.Transaction{Title: "DOMINOS PIZZA", Amount: -2100,
+			Tags: []string{"restaurants"}}
+		for _, s := range c.Suggest(tr) {
+			if s.Tag == "restaurants" {
+				t.Errorf("wants existing tag left out, got %+v", s)
+			}
+		}
+	})
+
+	t.Run("Applied with rules", func(t *testing.T) {
+		rules := []waukeen.Rule{
+			{Type: waukeen.ReplaceRule, Match: "LOBLAWS", Result: "Loblaws"},
+		}
+
+		tr := &waukeen.Transaction{Title: "LOBLAWS", Amount: -7200}
+		waukeen.ApplyRules(tr, rules, c)
+
+		want := &waukeen.Transaction{Title: "LOBLAWS", Alias: "Loblaws",
+			Amount: -7200, Tags: []string{"groceries"}}
+		if !reflect.DeepEqual(want, tr) {
+			t.Errorf("wants %+v, got %+v", want, tr)
+		}
+	})
+
+	t.Run("Zero threshold", func(t *testing.T) {
+		c := &Classifier{Rules: transformer.Text{}}
+		c.Load(model)
+		for _, s := range c.Suggest(&waukeen.Transaction{Title: "LOBLAWS", Amount: -7200}) {
+			if s.Confident {
+				t.Errorf("wants no confident suggestion, got %+v", s)
+			}
+		}
+	})
+}
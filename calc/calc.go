@@ -1,47 +1,244 @@
 package calc
 
 import (
+	"time"
+
 	"github.com/bradfitz/slice" // FIX Go 1.8 has built-in slice sort
 	"github.com/luizbranco/waukeen"
 )
 
+// Budgeter sums transactions by tag and month. Transactions before start
+// only count towards what rollover tags carry into the first month.
 type Budgeter struct{}
 
-func (Budgeter) Calculate(months int, trs []waukeen.Transaction,
-	tags []waukeen.Tag) []waukeen.Budget {
+func (Budgeter) Calculate(start time.Time, months int,
+	trs []waukeen.Transaction, tags []waukeen.Tag) []waukeen.Budget {
 
 	var budget []waukeen.Budget
 
-	m := make(map[string]waukeen.Budget)
+	start = firstOfMonth(start)
+	m := make(map[string]*waukeen.Budget)
+
+	find := func(tag string) *waukeen.Budget {
+		b, ok := m[tag]
+		if !ok {
+			b = &waukeen.Budget{Tag: tag, Months: make([]waukeen.BudgetMonth, months)}
+			for i := range b.Months {
+				b.Months[i].Month = start.AddDate(0, i, 0)
+			}
+			m[tag] = b
+		}
+		return b
+	}
+
+	income := incomeTags(tags)
+	tree := waukeen.NewTagTree(tags)
+
+	// earlier is what was spent by tag before start, by month counted back
+	// from it, and first the month of the earliest transaction of the tag.
+	earlier := make(map[string]map[int]int64)
+	first := make(map[string]int)
 
 	for _, tr := range trs {
+		earned := isIncome(tr, income)
+
 		if len(tr.Tags) == 0 {
 			tr.Tags = []string{"other"}
 		}
 
-		for _, tag := range tr.Tags {
-			b := m[tag]
+		month := monthsBetween(start, tr.Date)
+
+		if month < 0 {
+			for _, tag := range withAncestors(tree, tr.Tags) {
+				if earlier[tag] == nil {
+					earlier[tag] = make(map[int]int64)
+				}
+				if month < first[tag] {
+					first[tag] = month
+				}
+				if !earned {
+					earlier[tag][month] -= tr.Amount
+				}
+			}
+			continue
+		}
+
+		for _, tag := range withAncestors(tree, tr.Tags) {
+			b := find(tag)
 			b.Transactions++
-			b.Spent += (tr.Amount * -1)
-			b.Tag = tag
-			m[tag] = b
+
+			bm := &waukeen.BudgetMonth{}
+			if month >= 0 && month < months {
+				bm = &b.Months[month]
+			}
+
+			if earned {
+				b.Income += tr.Amount
+				bm.Income += tr.Amount
+			} else {
+				b.Spent -= tr.Amount
+				bm.Spent -= tr.Amount
+			}
 		}
 	}
 
 	for _, t := range tags {
-		b := m[t.Name]
-		b.Tag = t.Name
-		b.Planned = t.MonthlyBudget * int64(months)
-		m[t.Name] = b
+		b := find(t.Name)
+		b.Parent = t.Parent
+
+		if t.Income {
+			for i := range b.Months {
+				b.Months[i].Expected = planned(tree, t, b.Months[i].Month)
+				b.Expected += b.Months[i].Expected
+			}
+			continue
+		}
+
+		var carry int64
+		if t.Rollover {
+			for i := first[t.Name]; i < 0; i++ {
+				m := start.AddDate(0, i, 0)
+				left := planned(tree, t, m) + carry - earlier[t.Name][i]
+				carry = capCarry(left, t.RolloverCap)
+			}
+		}
+		for i := range b.Months {
+			b.Months[i].Planned = planned(tree, t, b.Months[i].Month)
+			b.Planned += b.Months[i].Planned
+			if t.Rollover {
+				b.Months[i].Carry = carry
+				carry = capCarry(b.Months[i].Left(), t.RolloverCap)
+			}
+		}
+		b.Carry = carry
 	}
 
 	for _, b := range m {
-		budget = append(budget, b)
+		budget = append(budget, *b)
 	}
 
 	slice.Sort(budget, func(i, j int) bool {
+		if budget[i].Spent == budget[j].Spent {
+			return budget[i].Tag < budget[j].Tag
+		}
 		return budget[i].Spent > budget[j].Spent
 	})
 
 	return budget
 }
+
+func (Budgeter) Totals(start time.Time, months int,
+	trs []waukeen.Transaction, tags []waukeen.Tag) []waukeen.BudgetTotals {
+
+	start = firstOfMonth(start)
+	income := incomeTags(tags)
+	tree := waukeen.NewTagTree(tags)
+	totals := make([]waukeen.BudgetTotals, months)
+
+	// income tags below another one are already planned as part of it
+	var roots []waukeen.Tag
+	for _, t := range tags {
+		ancestors := tree.Ancestors(t.Name)
+		if t.Income && (len(ancestors) == 0 || !income[ancestors[0]]) {
+			roots = append(roots, t)
+		}
+	}
+
+	for i := range totals {
+		totals[i].Month = start.AddDate(0, i, 0)
+		for _, t := range roots {
+			totals[i].Expected += planned(tree, t, totals[i].Month)
+		}
+	}
+
+	for _, tr := range trs {
+		i := monthsBetween(start, tr.Date)
+		if i < 0 || i >= months {
+			continue
+		}
+
+		if isIncome(tr, income) {
+			totals[i].Income += tr.Amount
+		} else {
+			totals[i].Expenses -= tr.Amount
+		}
+	}
+
+	return totals
+}
+
+// withAncestors adds the ancestors of the tags, each tag once.
+func withAncestors(tree *waukeen.TagTree, tags []string) []string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		for _, t := range append([]string{tag}, tree.Ancestors(tag)...) {
+			if !seen[t] {
+				seen[t] = true
+				list = append(list, t)
+			}
+		}
+	}
+	return list
+}
+
+// planned is the budget of the tag for the month, or the sum of the ones of
+// its children of the same kind when it has none.
+func planned(tree *waukeen.TagTree, t waukeen.Tag, month time.Time) int64 {
+	if t.MonthlyBudget != 0 || len(t.Budgets) > 0 {
+		return t.Budget(month)
+	}
+
+	var n int64
+	for _, name := range tree.Children(t.Name) {
+		c, _ := tree.Tag(name)
+		if c.Income == t.Income {
+			n += planned(tree, c, month)
+		}
+	}
+	return n
+}
+
+func incomeTags(tags []waukeen.Tag) map[string]bool {
+	income := make(map[string]bool)
+	for _, t := range tags {
+		if t.Income {
+			income[t.Name] = true
+		}
+	}
+	return income
+}
+
+// isIncome reports whether the transaction is a credit either untagged or
+// tagged as income, other credits are refunds netted against their tags.
+func isIncome(tr waukeen.Transaction, income map[string]bool) bool {
+	if tr.Amount <= 0 {
+		return false
+	}
+	if len(tr.Tags) == 0 {
+		return true
+	}
+	for _, tag := range tr.Tags {
+		if income[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+// monthsBetween counts the months from start to the month of t.
+func monthsBetween(start, t time.Time) int {
+	return (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+}
+
+func capCarry(carry, max int64) int64 {
+	switch {
+	case max <= 0:
+		return carry
+	case carry > max:
+		return max
+	case carry < -max:
+		return -max
+	}
+	return carry
+}
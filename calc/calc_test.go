@@ -3,6 +3,7 @@ package calc
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/luizbranco/waukeen"
 )
@@ -14,7 +15,28 @@ func TestBudgetCalculatorInterface(t *testing.T) {
 func TestCalculate(t *testing.T) {
 	b := Budgeter{}
 
+	month := func(m int, planned, spent, carry int64) waukeen.BudgetMonth {
+		return waukeen.BudgetMonth{
+			Month:   time.Date(2017, time.Month(m), 1, 0, 0, 0, 0, time.UTC),
+			Planned: planned,
+			Spent:   spent,
+			Carry:   carry,
+		}
+	}
+
+	income := func(m waukeen.BudgetMonth, expected, income int64) waukeen.BudgetMonth {
+		m.Expected = expected
+		m.Income = income
+		return m
+	}
+
+	jan := time.Date(2017, 1, 12, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2017, 2, 3, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2017, 3, 28, 0, 0, 0, 0, time.UTC)
+	apr := time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC)
+
 	testCases := []struct {
+		start        time.Time
 		months       int
 		transactions []waukeen.Transaction
 		tags         []waukeen.Tag
@@ -22,11 +44,12 @@ func TestCalculate(t *testing.T) {
 	}{
 		{}, // empty case
 		{
+			start:  jan,
 			months: 1,
 			transactions: []waukeen.Transaction{
-				{Amount: -1000, Tags: []string{"food", "pizza"}},
-				{Amount: -5000, Tags: []string{"travel"}},
-				{Amount: -4500, Tags: []string{"gift", "pizza"}},
+				{Date: jan, Amount: -1000, Tags: []string{"food", "pizza"}},
+				{Date: jan, Amount: -5000, Tags: []string{"travel"}},
+				{Date: jan, Amount: -4500, Tags: []string{"gift", "pizza"}},
 			},
 			tags: []waukeen.Tag{
 				{Name: "food", MonthlyBudget: 2000},
@@ -34,36 +57,261 @@ func TestCalculate(t *testing.T) {
 				{Name: "liquor", MonthlyBudget: 1000},
 			},
 			budget: []waukeen.Budget{
-				{Tag: "pizza", Transactions: 2, Planned: 5000, Spent: 5500},
-				{Tag: "travel", Transactions: 1, Planned: 0, Spent: 5000},
-				{Tag: "gift", Transactions: 1, Planned: 0, Spent: 4500},
-				{Tag: "food", Transactions: 1, Planned: 2000, Spent: 1000},
-				{Tag: "liquor", Transactions: 0, Planned: 1000, Spent: 0},
+				{Tag: "pizza", Transactions: 2, Planned: 5000, Spent: 5500,
+					Months: []waukeen.BudgetMonth{month(1, 5000, 5500, 0)}},
+				{Tag: "travel", Transactions: 1, Planned: 0, Spent: 5000,
+					Months: []waukeen.BudgetMonth{month(1, 0, 5000, 0)}},
+				{Tag: "gift", Transactions: 1, Planned: 0, Spent: 4500,
+					Months: []waukeen.BudgetMonth{month(1, 0, 4500, 0)}},
+				{Tag: "food", Transactions: 1, Planned: 2000, Spent: 1000,
+					Months: []waukeen.BudgetMonth{month(1, 2000, 1000, 0)}},
+				{Tag: "liquor", Transactions: 0, Planned: 1000, Spent: 0,
+					Months: []waukeen.BudgetMonth{month(1, 1000, 0, 0)}},
 			},
 		},
 		{
+			start:  jan,
 			months: 3,
 			transactions: []waukeen.Transaction{
-				{Amount: -1000, Tags: []string{"food"}},
-				{Amount: -5000},
-				{Amount: -250},
+				{Date: jan, Amount: -1000, Tags: []string{"food"}},
+				{Date: feb, Amount: -5000},
+				{Date: feb, Amount: -250},
 			},
 			tags: []waukeen.Tag{
 				{Name: "food", MonthlyBudget: 2000},
 			},
 			budget: []waukeen.Budget{
-				{Tag: "other", Transactions: 2, Planned: 0, Spent: 5250},
-				{Tag: "food", Transactions: 1, Planned: 6000, Spent: 1000},
+				{Tag: "other", Transactions: 2, Planned: 0, Spent: 5250,
+					Months: []waukeen.BudgetMonth{
+						month(1, 0, 0, 0), month(2, 0, 5250, 0), month(3, 0, 0, 0),
+					}},
+				{Tag: "food", Transactions: 1, Planned: 6000, Spent: 1000,
+					Months: []waukeen.BudgetMonth{
+						month(1, 2000, 1000, 0), month(2, 2000, 0, 0), month(3, 2000, 0, 0),
+					}},
+			},
+		},
+		{ // month budgets
+			start:  jan,
+			months: 3,
+			transactions: []waukeen.Transaction{
+				{Date: feb, Amount: -9000, Tags: []string{"heating"}},
+			},
+			tags: []waukeen.Tag{
+				{Name: "heating", MonthlyBudget: 3000, Budgets: []waukeen.TagBudget{
+					{Month: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), Amount: 10000},
+					{Month: time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC), Amount: 8000},
+				}},
+			},
+			budget: []waukeen.Budget{
+				{Tag: "heating", Transactions: 1, Planned: 21000, Spent: 9000,
+					Months: []waukeen.BudgetMonth{
+						month(1, 10000, 0, 0), month(2, 8000, 9000, 0), month(3, 3000, 0, 0),
+					}},
+			},
+		},
+		{ // income and refunds
+			start:  jan,
+			months: 2,
+			transactions: []waukeen.Transaction{
+				{Date: jan, Amount: 300000, Tags: []string{"salary"}},
+				{Date: feb, Amount: 310000, Tags: []string{"salary"}},
+				{Date: jan, Amount: -8000, Tags: []string{"clothes"}},
+				{Date: feb, Amount: 3000, Tags: []string{"clothes"}},
+				{Date: feb, Amount: 1500},
+			},
+			tags: []waukeen.Tag{
+				{Name: "salary", MonthlyBudget: 300000, Income: true},
+				{Name: "clothes", MonthlyBudget: 5000},
+			},
+			budget: []waukeen.Budget{
+				{Tag: "clothes", Transactions: 2, Planned: 10000, Spent: 5000,
+					Months: []waukeen.BudgetMonth{
+						month(1, 5000, 8000, 0), month(2, 5000, -3000, 0),
+					}},
+				{Tag: "other", Transactions: 1, Income: 1500,
+					Months: []waukeen.BudgetMonth{
+						month(1, 0, 0, 0), income(month(2, 0, 0, 0), 0, 1500),
+					}},
+				{Tag: "salary", Transactions: 2, Expected: 600000, Income: 610000,
+					Months: []waukeen.BudgetMonth{
+						income(month(1, 0, 0, 0), 300000, 300000),
+						income(month(2, 0, 0, 0), 300000, 310000),
+					}},
+			},
+		},
+		{ // hierarchy
+			start:  jan,
+			months: 1,
+			transactions: []waukeen.Transaction{
+				{Date: jan, Amount: -3000, Tags: []string{"pizza"}},
+				{Date: jan, Amount: -2000, Tags: []string{"restaurants", "pizza"}},
+				{Date: jan, Amount: -6000, Tags: []string{"groceries"}},
+				{Date: jan, Amount: -4000, Tags: []string{"bills"}},
+			},
+			tags: []waukeen.Tag{
+				{Name: "food"},
+				{Name: "restaurants", Parent: "food", MonthlyBudget: 8000},
+				{Name: "pizza", Parent: "restaurants"},
+				{Name: "groceries", Parent: "food", MonthlyBudget: 10000},
+				{Name: "home", MonthlyBudget: 5000},
+				{Name: "bills", Parent: "home", MonthlyBudget: 20000},
+			},
+			budget: []waukeen.Budget{
+				{Tag: "food", Transactions: 3, Planned: 18000, Spent: 11000,
+					Months: []waukeen.BudgetMonth{month(1, 18000, 11000, 0)}},
+				{Tag: "groceries", Parent: "food", Transactions: 1, Planned: 10000, Spent: 6000,
+					Months: []waukeen.BudgetMonth{month(1, 10000, 6000, 0)}},
+				{Tag: "pizza", Parent: "restaurants", Transactions: 2, Planned: 0, Spent: 5000,
+					Months: []waukeen.BudgetMonth{month(1, 0, 5000, 0)}},
+				{Tag: "restaurants", Parent: "food", Transactions: 2, Planned: 8000, Spent: 5000,
+					Months: []waukeen.BudgetMonth{month(1, 8000, 5000, 0)}},
+				{Tag: "bills", Parent: "home", Transactions: 1, Planned: 20000, Spent: 4000,
+					Months: []waukeen.BudgetMonth{month(1, 20000, 4000, 0)}},
+				{Tag: "home", Transactions: 1, Planned: 5000, Spent: 4000,
+					Months: []waukeen.BudgetMonth{month(1, 5000, 4000, 0)}},
+			},
+		},
+		{ // rollover
+			start:  feb.AddDate(0, -1, 0),
+			months: 3,
+			transactions: []waukeen.Transaction{
+				{Date: jan, Amount: -6000, Tags: []string{"groceries"}},
+				{Date: feb, Amount: -15000, Tags: []string{"groceries"}},
+				{Date: mar, Amount: -8000, Tags: []string{"groceries"}},
+				{Date: apr, Amount: -500, Tags: []string{"groceries"}},
+				{Date: feb, Amount: -9000, Tags: []string{"fun"}},
+				{Date: mar, Amount: -1000, Tags: []string{"fun"}},
+			},
+			tags: []waukeen.Tag{
+				{Name: "groceries", MonthlyBudget: 10000, Rollover: true},
+				{Name: "fun", MonthlyBudget: 5000, Rollover: true, RolloverCap: 2000},
+			},
+			budget: []waukeen.Budget{
+				{Tag: "groceries", Transactions: 4, Planned: 30000, Spent: 29500, Carry: 1000,
+					Months: []waukeen.BudgetMonth{
+						month(1, 10000, 6000, 0), month(2, 10000, 15000, 4000),
+						month(3, 10000, 8000, -1000),
+					}},
+				{Tag: "fun", Transactions: 2, Planned: 15000, Spent: 10000, Carry: 2000,
+					Months: []waukeen.BudgetMonth{
+						month(1, 5000, 0, 0), month(2, 5000, 9000, 2000),
+						month(3, 5000, 1000, -2000),
+					}},
+			},
+		},
+		{ // rollover carried into a single month from the months before
+			start:  mar,
+			months: 1,
+			transactions: []waukeen.Transaction{
+				{Date: jan, Amount: -6000, Tags: []string{"groceries"}},
+				{Date: feb, Amount: -15000, Tags: []string{"groceries"}},
+				{Date: feb, Amount: 2000, Tags: []string{"groceries"}},
+				{Date: mar, Amount: -8000, Tags: []string{"groceries"}},
+				{Date: feb, Amount: -1000, Tags: []string{"fun"}},
+				{Date: mar, Amount: -1000, Tags: []string{"fun"}},
+			},
+			tags: []waukeen.Tag{
+				{Name: "groceries", MonthlyBudget: 10000, Rollover: true},
+				{Name: "fun", MonthlyBudget: 5000, Rollover: true, RolloverCap: 2000},
+			},
+			budget: []waukeen.Budget{
+				{Tag: "groceries", Transactions: 1, Planned: 10000, Spent: 8000, Carry: 3000,
+					Months: []waukeen.BudgetMonth{month(3, 10000, 8000, 1000)}},
+				{Tag: "fun", Transactions: 1, Planned: 5000, Spent: 1000, Carry: 2000,
+					Months: []waukeen.BudgetMonth{month(3, 5000, 1000, 2000)}},
 			},
 		},
 	}
 
 	for _, tc := range testCases {
 		want := tc.budget
-		got := b.Calculate(tc.months, tc.transactions, tc.tags)
+		got := b.Calculate(tc.start, tc.months, tc.transactions, tc.tags)
 
 		if !reflect.DeepEqual(want, got) {
 			t.Errorf("wants %+v, got %+v", want, got)
 		}
 	}
 }
+
+func TestTotals(t *testing.T) {
+	jan := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	trs := []waukeen.Transaction{
+		{Date: jan, Amount: 300000, Tags: []string{"salary"}},
+		{Date: jan, Amount: -100000, Tags: []string{"rent", "home"}},
+		{Date: jan, Amount: -20000, Tags: []string{"clothes"}},
+		{Date: jan, Amount: 5000, Tags: []string{"clothes"}},
+		{Date: feb, Amount: 1000},
+		{Date: feb, Amount: -2000},
+		{Date: feb.AddDate(0, 1, 0), Amount: 300000, Tags: []string{"salary"}},
+	}
+
+	tags := []waukeen.Tag{{Name: "salary", MonthlyBudget: 300000, Income: true}}
+
+	want := []waukeen.BudgetTotals{
+		{Month: jan, Expected: 300000, Income: 300000, Expenses: 115000},
+		{Month: feb, Expected: 300000, Income: 1000, Expenses: 2000},
+	}
+
+	got := Budgeter{}.Totals(jan.AddDate(0, 0, 20), 2, trs, tags)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants %+v, got %+v", want, got)
+	}
+
+	if rate := got[0].SavingsRate(); rate != 185000.0/300000 {
+		t.Errorf("wants savings rate %f, got %f", 185000.0/300000, rate)
+	}
+
+	if rate := got[1].SavingsRate(); rate != -1 {
+		t.Errorf("wants savings rate -1, got %f", rate)
+	}
+}
+
+func TestTotalsIncomeTree(t *testing.T) {
+	jan := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		tags     []waukeen.Tag
+		expected int64
+	}{
+		{
+			name: "parent budget",
+			tags: []waukeen.Tag{
+				{Name: "income", MonthlyBudget: 5000, Income: true},
+				{Name: "salary", Parent: "income", MonthlyBudget: 4000, Income: true},
+				{Name: "bonus", Parent: "income", MonthlyBudget: 1000, Income: true},
+			},
+			expected: 5000,
+		},
+		{
+			name: "children budgets",
+			tags: []waukeen.Tag{
+				{Name: "income", Income: true},
+				{Name: "salary", Parent: "income", MonthlyBudget: 4000, Income: true},
+				{Name: "bonus", Parent: "income", MonthlyBudget: 1000, Income: true},
+			},
+			expected: 5000,
+		},
+		{
+			name: "below an expense",
+			tags: []waukeen.Tag{
+				{Name: "home", MonthlyBudget: 2000},
+				{Name: "rent", Parent: "home", MonthlyBudget: 1500, Income: true},
+				{Name: "salary", MonthlyBudget: 4000, Income: true},
+			},
+			expected: 5500,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Budgeter{}.Totals(jan, 1, nil, tc.tags)
+			if got[0].Expected != tc.expected {
+				t.Errorf("wants expected %d, got %d", tc.expected, got[0].Expected)
+			}
+		})
+	}
+}
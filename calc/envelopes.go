@@ -0,0 +1,167 @@
+package calc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/luizbranco/waukeen"
+)
+
+// EnvelopeBudgeter is a zero-based EnvelopeCalculator. Income is to be
+// assigned to tag envelopes, other transactions draw down, or refund, the
+// envelope of their deepest tag and what is left stays in the envelope the
+// month after.
+type EnvelopeBudgeter struct{}
+
+func (EnvelopeBudgeter) Envelopes(month time.Time, trs []waukeen.Transaction,
+	tags []waukeen.Tag, ledger waukeen.EnvelopeLedger) waukeen.EnvelopeMonth {
+
+	month = firstOfMonth(month)
+	l := newLedger(trs, tags, ledger)
+	em := waukeen.EnvelopeMonth{Month: month}
+
+	for m, n := range l.income {
+		if !m.After(month) {
+			em.ToBeAssigned += n
+		}
+	}
+	em.Income = l.income[month]
+
+	for _, assigned := range l.assigned {
+		for m, n := range assigned {
+			if !m.After(month) {
+				em.ToBeAssigned -= n
+			}
+		}
+	}
+
+	for _, tag := range l.tags(tags) {
+		e := waukeen.Envelope{
+			Tag:      tag,
+			Carry:    l.balance(tag, month),
+			Assigned: l.assigned[tag][month],
+			Moved:    l.moved[tag][month],
+			Spent:    l.spent[tag][month],
+		}
+		em.Assigned += e.Assigned
+		em.Envelopes = append(em.Envelopes, e)
+	}
+
+	return em
+}
+
+// ledger sums the money of every envelope by month, its maps go from tag to
+// month to amount.
+type ledger struct {
+	income     map[time.Time]int64
+	assigned   map[string]map[time.Time]int64
+	moved      map[string]map[time.Time]int64
+	spent      map[string]map[time.Time]int64
+	incomeTags map[string]bool
+}
+
+func newLedger(trs []waukeen.Transaction, tags []waukeen.Tag,
+	el waukeen.EnvelopeLedger) *ledger {
+
+	l := &ledger{
+		income:     make(map[time.Time]int64),
+		assigned:   make(map[string]map[time.Time]int64),
+		moved:      make(map[string]map[time.Time]int64),
+		spent:      make(map[string]map[time.Time]int64),
+		incomeTags: incomeTags(tags),
+	}
+	tree := waukeen.NewTagTree(tags)
+
+	add := func(sums map[string]map[time.Time]int64, tag string, m time.Time, n int64) {
+		if sums[tag] == nil {
+			sums[tag] = make(map[time.Time]int64)
+		}
+		sums[tag][firstOfMonth(m)] += n
+	}
+
+	for _, t := range trs {
+		m := firstOfMonth(t.Date)
+		if isIncome(t, l.incomeTags) {
+			l.income[m] += t.Amount
+			continue
+		}
+
+		add(l.spent, envelope(tree, t.Tags, l.incomeTags), m, -t.Amount)
+	}
+
+	for _, a := range el.Assignments {
+		add(l.assigned, a.Tag, a.Month, a.Amount)
+	}
+
+	for _, mv := range el.Moves {
+		add(l.moved, mv.From, mv.Month, -mv.Amount)
+		add(l.moved, mv.To, mv.Month, mv.Amount)
+	}
+
+	return l
+}
+
+// envelope is the one spending draws from: the deepest of its tags in the
+// tree, the first by name among tags as deep. Income tags have no envelope,
+// spending with only those goes to other.
+func envelope(tree *waukeen.TagTree, tags []string, income map[string]bool) string {
+	tag, depth := "other", -1
+	for _, name := range tags {
+		if income[name] {
+			continue
+		}
+		d := len(tree.Ancestors(name))
+		if d > depth || (d == depth && name < tag) {
+			tag, depth = name, d
+		}
+	}
+	return tag
+}
+
+// balance is what was left in the envelope before the month.
+func (l *ledger) balance(tag string, month time.Time) int64 {
+	var n int64
+	for _, sums := range []map[time.Time]int64{l.assigned[tag], l.moved[tag]} {
+		for m, amount := range sums {
+			if m.Before(month) {
+				n += amount
+			}
+		}
+	}
+	for m, amount := range l.spent[tag] {
+		if m.Before(month) {
+			n -= amount
+		}
+	}
+	return n
+}
+
+// tags are the names of the tags given and of any other envelope with money,
+// sorted. Income tags have no envelope.
+func (l *ledger) tags(tags []waukeen.Tag) []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	add := func(name string) {
+		if !seen[name] && !l.incomeTags[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, t := range tags {
+		add(t.Name)
+	}
+	for _, sums := range []map[string]map[time.Time]int64{l.assigned, l.moved, l.spent} {
+		for tag := range sums {
+			add(tag)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
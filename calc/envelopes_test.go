@@ -0,0 +1,102 @@
+package calc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luizbranco/waukeen"
+)
+
+func TestEnvelopeBudgeterInterface(t *testing.T) {
+	var _ waukeen.EnvelopeCalculator = EnvelopeBudgeter{}
+}
+
+func TestEnvelopeBudgeter(t *testing.T) {
+	jan := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	trs := []waukeen.Transaction{
+		{Date: jan.AddDate(0, 0, 2), Amount: 300000, Tags: []string{"salary"}},
+		{Date: jan.AddDate(0, 0, 3), Amount: -150000, Tags: []string{"rent"}},
+		{Date: jan.AddDate(0, 0, 5), Amount: -10000, Tags: []string{"food", "pizza"}},
+		{Date: jan.AddDate(0, 0, 9), Amount: -20000, Tags: []string{"food"}},
+		{Date: jan.AddDate(0, 0, 9), Amount: -1000},
+		{Date: feb.AddDate(0, 0, 4), Amount: -50000, Tags: []string{"food"}},
+		{Date: feb.AddDate(0, 0, 6), Amount: 5000, Tags: []string{"food"}},
+		{Date: feb.AddDate(0, 0, 20), Amount: -5000, Tags: []string{"fun"}},
+	}
+
+	tags := []waukeen.Tag{
+		{Name: "rent"}, {Name: "food"}, {Name: "fun"}, {Name: "salary", Income: true},
+	}
+
+	ledger := waukeen.EnvelopeLedger{
+		Assignments: []waukeen.EnvelopeAssignment{
+			{Tag: "rent", Month: jan, Amount: 150000},
+			{Tag: "food", Month: jan, Amount: 50000},
+			{Tag: "food", Month: feb, Amount: 40000},
+		},
+		Moves: []waukeen.EnvelopeMove{
+			{From: "food", To: "fun", Month: feb, Amount: 10000},
+		},
+	}
+
+	t.Run("Envelopes", func(t *testing.T) {
+		want := waukeen.EnvelopeMonth{
+			Month:        feb,
+			Income:       0,
+			Assigned:     40000,
+			ToBeAssigned: 60000,
+			Envelopes: []waukeen.Envelope{
+				{Tag: "food", Carry: 20000, Assigned: 40000, Moved: -10000, Spent: 45000},
+				{Tag: "fun", Moved: 10000, Spent: 5000},
+				{Tag: "other", Carry: -1000},
+				{Tag: "rent"},
+			},
+		}
+
+		got := EnvelopeBudgeter{}.Envelopes(feb.AddDate(0, 0, 14), trs, tags, ledger)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("wants\n%+v\ngot\n%+v", want, got)
+		}
+
+		if b := got.Envelopes[0].Balance(); b != 5000 {
+			t.Errorf("wants food balance 5000, got %d", b)
+		}
+	})
+	t.Run("Envelope Tag", func(t *testing.T) {
+		tags := []waukeen.Tag{
+			{Name: "food"}, {Name: "restaurants", Parent: "food"},
+			{Name: "pizza", Parent: "restaurants"}, {Name: "fun"},
+			{Name: "salary", Income: true},
+		}
+
+		testCases := []struct {
+			tags []string
+			want string
+		}{
+			{tags: []string{"food", "restaurants"}, want: "restaurants"},
+			{tags: []string{"pizza", "food", "restaurants"}, want: "pizza"},
+			{tags: []string{"fun", "food"}, want: "food"},
+			{tags: []string{"salary"}, want: "other"},
+			{tags: []string{"salary", "fun"}, want: "fun"},
+		}
+
+		for _, tc := range testCases {
+			trs := []waukeen.Transaction{{Date: jan, Amount: -1000, Tags: tc.tags}}
+			got := EnvelopeBudgeter{}.Envelopes(jan, trs, tags, waukeen.EnvelopeLedger{})
+
+			for _, e := range got.Envelopes {
+				want := int64(0)
+				if e.Tag == tc.want {
+					want = 1000
+				}
+				if e.Spent != want {
+					t.Errorf("wants %v drawn from %s, got %d spent from %s",
+						tc.tags, tc.want, e.Spent, e.Tag)
+				}
+			}
+		}
+	})
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/luizbranco/waukeen/bayes"
 	"github.com/luizbranco/waukeen/calc"
+	"github.com/luizbranco/waukeen/csv"
+	"github.com/luizbranco/waukeen/importer"
 	"github.com/luizbranco/waukeen/json"
+	"github.com/luizbranco/waukeen/qif"
 	"github.com/luizbranco/waukeen/sqlite"
 	"github.com/luizbranco/waukeen/transformer"
 	"github.com/luizbranco/waukeen/web/html"
@@ -15,22 +22,130 @@ import (
 )
 
 func main() {
+	profiles := flag.String("csv", "", "CSV mapping profiles file")
+	threshold := flag.Float64("tag-threshold", 0, "confidence from which suggested tags are applied on import, 0 never applies them")
+	migrate := flag.String("migrate", "", "show migrations \"status\" or apply pending ones with \"up\", then exit")
+	flag.Parse()
+
+	if *migrate != "" {
+		err := migrations("waukeen.db", *migrate)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	db, err := sqlite.New("waukeen.db")
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	importers, err := statementsImporters(*profiles)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	model, err := db.FindTagModel()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	classifier := &bayes.Classifier{Rules: transformer.Text{}, Threshold: *threshold}
+	classifier.Load(model)
+
 	srv := &server.Server{
-		DB:                 db,
-		Template:           html.New("web/templates"),
-		StatementsImporter: xml.Statement{},
-		RulesImporter:      json.Rules{},
-		Transformer:        transformer.Text{},
-		BudgetCalculator:   calc.Budgeter{},
+		DB:                  db,
+		Template:            html.New("web/templates"),
+		StatementsImporters: importers,
+		RulesImporter:       json.Rules{},
+		RulesExporter:       json.Rules{},
+		Transformer:         classifier,
+		Classifier:          classifier,
+		BudgetCalculator:    calc.Budgeter{},
+		EnvelopeCalculator:  calc.EnvelopeBudgeter{},
 	}
 	mux := srv.NewServeMux()
 
 	fmt.Println("Server listening on port 8080")
 	log.Fatal(http.ListenAndServe(":8080", mux))
 }
+
+func migrations(path, cmd string) error {
+	db, err := sqlite.Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch cmd {
+	case "status":
+	case "up":
+		err = db.Migrate()
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %s", cmd)
+	}
+
+	list, err := db.Migrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range list {
+		status := "pending"
+		if !m.AppliedAt.IsZero() {
+			status = "applied " + m.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%4d  %-30s %s\n", m.Version, m.Name, status)
+	}
+
+	return nil
+}
+
+func statementsImporters(path string) (*importer.Registry, error) {
+	registry := importer.New(
+		importer.Format{
+			Name:       "ofx",
+			Extensions: []string{".ofx", ".qfx"},
+			Sniff:      importer.SniffOFX,
+			Importer:   xml.Statement{},
+		},
+		importer.Format{
+			Name:       "qif",
+			Extensions: []string{".qif"},
+			Sniff:      importer.SniffQIF,
+			Importer:   qif.Statement{},
+		},
+	)
+
+	if path == "" {
+		return registry, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	profiles, err := csv.Profiles(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range profiles {
+		registry.Register(importer.Format{
+			Name:       "csv:" + p.Name,
+			Extensions: []string{".csv"},
+			Sniff:      p.Sniff,
+			Importer:   csv.Statement{Profile: p},
+		})
+	}
+
+	return registry, nil
+}
@@ -0,0 +1,320 @@
+package csv
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/pkg/errors"
+)
+
+// Profile maps the columns of a bank CSV export to transaction fields.
+// Columns are 1-based, a zero value means the file doesn't have that column.
+// Headers are the names in the header row of the export, files starting with
+// them are detected as the profile and, as with Header, the row is skipped.
+type Profile struct {
+	Name        string              `json:"name"`
+	Account     string              `json:"account"`
+	AccountType waukeen.AccountType `json:"account_type"`
+	Currency    string              `json:"currency"`
+	Header      bool                `json:"header"`
+	Headers     []string            `json:"headers,omitempty"`
+	Comma       string              `json:"comma"`
+	DateFormat  string              `json:"date_format"`
+	Decimal     string              `json:"decimal"`
+	ID          int                 `json:"id"`
+	Date        int                 `json:"date"`
+	Payee       int                 `json:"payee"`
+	Memo        int                 `json:"memo"`
+	Amount      int                 `json:"amount"`
+	Debit       int                 `json:"debit"`
+	Credit      int                 `json:"credit"`
+}
+
+type Statement struct {
+	Profile Profile
+}
+
+// Profiles decodes a list of mapping profiles from a JSON file.
+func Profiles(in io.Reader) ([]Profile, error) {
+	var profiles []Profile
+	dec := json.NewDecoder(in)
+	err := dec.Decode(&profiles)
+	return profiles, err
+}
+
+func (s Statement) Import(in io.Reader) ([]waukeen.Statement, error) {
+	p := s.Profile
+
+	if p.Account == "" {
+		return nil, errors.New("csv profile missing account number")
+	}
+
+	if p.Date == 0 || p.Payee == 0 {
+		return nil, errors.New("csv profile missing date or payee column")
+	}
+
+	if p.Amount == 0 && p.Debit == 0 && p.Credit == 0 {
+		return nil, errors.New("csv profile missing amount column")
+	}
+
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	if p.Comma != "" {
+		r.Comma = []rune(p.Comma)[0]
+	}
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "read csv")
+	}
+
+	// line numbers in errors count the header row skipped
+	line := 1
+	if (p.Header || len(p.Headers) > 0) && len(records) > 0 {
+		records = records[1:]
+		line++
+	}
+
+	stmt := waukeen.Statement{
+		Account: waukeen.Account{
+			Number:   p.Account,
+			Type:     p.AccountType,
+			Currency: p.Currency,
+		},
+	}
+
+	seen := make(map[string]int)
+
+	for i, rec := range records {
+		if blank(rec) {
+			continue
+		}
+
+		t, err := p.transaction(rec)
+		if err != nil {
+			return nil, errors.Wrapf(err, "csv line %d", i+line)
+		}
+
+		if t.FITID == "" {
+			id := p.hash(rec)
+			seen[id]++
+			t.FITID = fmt.Sprintf("%s-%d", id, seen[id])
+		}
+
+		stmt.Transactions = append(stmt.Transactions, t)
+	}
+
+	return []waukeen.Statement{stmt}, nil
+}
+
+// Sniff reports whether the first line of a file is the header row of the
+// profile, profiles without Headers never match.
+func (p Profile) Sniff(head []byte) bool {
+	if len(p.Headers) == 0 {
+		return false
+	}
+
+	head = bytes.TrimPrefix(head, []byte("\ufeff"))
+	if i := bytes.IndexAny(head, "\r\n"); i >= 0 {
+		head = head[:i]
+	}
+
+	r := csv.NewReader(bytes.NewReader(head))
+	r.TrimLeadingSpace = true
+	if p.Comma != "" {
+		r.Comma = []rune(p.Comma)[0]
+	}
+
+	rec, err := r.Read()
+	if err != nil || len(rec) != len(p.Headers) {
+		return false
+	}
+
+	for i, h := range p.Headers {
+		if !strings.EqualFold(strings.TrimSpace(rec[i]), strings.TrimSpace(h)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Profile) transaction(rec []string) (waukeen.Transaction, error) {
+	t := waukeen.Transaction{
+		FITID:       column(rec, p.ID),
+		Title:       column(rec, p.Payee),
+		Description: column(rec, p.Memo),
+	}
+
+	layout := p.DateFormat
+	if layout == "" {
+		layout = "2006-01-02"
+	}
+
+	date, err := time.Parse(layout, column(rec, p.Date))
+	if err != nil {
+		return t, errors.Wrap(err, "invalid date")
+	}
+	t.Date = date
+
+	if p.Amount > 0 {
+		t.Amount, err = parseAmount(column(rec, p.Amount), p.Decimal)
+		if err != nil {
+			return t, err
+		}
+	} else {
+		debit, err := parseAmount(column(rec, p.Debit), p.Decimal)
+		if err != nil {
+			return t, err
+		}
+		credit, err := parseAmount(column(rec, p.Credit), p.Decimal)
+		if err != nil {
+			return t, err
+		}
+		t.Amount = credit - abs(debit)
+	}
+
+	if t.Amount < 0 {
+		t.Type = waukeen.Debit
+	} else {
+		t.Type = waukeen.Credit
+	}
+
+	return t, nil
+}
+
+// hash derives a stable transaction id from the mapped columns so the same
+// line always generates the same FITID when a file is imported again.
+func (p Profile) hash(rec []string) string {
+	fields := []string{p.Account}
+	for _, n := range []int{p.Date, p.Payee, p.Memo, p.Amount, p.Debit, p.Credit} {
+		fields = append(fields, column(rec, n))
+	}
+	sum := sha1.Sum([]byte(strings.Join(fields, "\x00")))
+	return fmt.Sprintf("%x", sum[:10])
+}
+
+func column(rec []string, n int) string {
+	if n <= 0 || n > len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[n-1])
+}
+
+func blank(rec []string) bool {
+	for _, f := range rec {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseAmount converts a formatted amount such as "-1.234,56", "(12.00)" or
+// "$ 9.99" to cents. Only digits, the grouping separator, a sign and leading
+// currency symbols are allowed.
+func parseAmount(s, decimal string) (int64, error) {
+	group := ","
+	if decimal == "" {
+		decimal = "."
+	}
+	if decimal == "," {
+		group = "."
+	}
+
+	in := s
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	signed := false
+	sign := func() {
+		s = strings.TrimSpace(s)
+		switch {
+		case signed:
+		case strings.HasPrefix(s, "-"):
+			negative, signed = !negative, true
+			s = s[1:]
+		case strings.HasSuffix(s, "-"):
+			negative, signed = !negative, true
+			s = s[:len(s)-1]
+		case strings.HasPrefix(s, "+"):
+			signed = true
+			s = s[1:]
+		}
+	}
+
+	// the sign may come before or after the currency symbol
+	sign()
+	s = strings.TrimLeftFunc(s, func(r rune) bool {
+		return unicode.Is(unicode.Sc, r) || unicode.IsSpace(r)
+	})
+	sign()
+
+	units, cents := s, ""
+	if i := strings.LastIndex(s, decimal); i >= 0 {
+		units, cents = s[:i], s[i+len(decimal):]
+	}
+	units = strings.Replace(units, group, "", -1)
+
+	if (units == "" && cents == "") || !digits(units) || !digits(cents) {
+		return 0, errors.Errorf("invalid amount %q", in)
+	}
+
+	if units == "" {
+		units = "0"
+	}
+
+	switch len(cents) {
+	case 0:
+		cents = "00"
+	case 1:
+		cents += "0"
+	case 2:
+	default:
+		return 0, errors.Errorf("invalid amount %q", in)
+	}
+
+	n, err := strconv.ParseInt(units+cents, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid amount %q", in)
+	}
+
+	if negative {
+		n *= -1
+	}
+
+	return n, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
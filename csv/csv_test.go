@@ -0,0 +1,269 @@
+package csv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luizbranco/waukeen"
+)
+
+func TestStatementInterface(t *testing.T) {
+	var _ waukeen.StatementsImporter = Statement{}
+}
+
+func TestStatementsImport(t *testing.T) {
+	t.Run("Invalid Profile", func(t *testing.T) {
+		importer := Statement{Profile: Profile{Date: 1, Payee: 2, Amount: 3}}
+		in := strings.NewReader(`2016-10-01,Pizza,-10.00`)
+		_, err := importer.Import(in)
+		if err == nil {
+			t.Error("wants error, got none")
+		}
+	})
+
+	t.Run("Error Line", func(t *testing.T) {
+		importer := Statement{Profile: Profile{
+			Account: "12345", Date: 1, Payee: 2, Amount: 3, Header: true,
+		}}
+		in := strings.NewReader("Date,Payee,Amount\n2016-10-01,Pizza,-10.00\n2016-10-02,Tacos,abc")
+		_, err := importer.Import(in)
+		if err == nil || !strings.Contains(err.Error(), "csv line 3") {
+			t.Errorf("wants error on csv line 3, got %v", err)
+		}
+	})
+
+	t.Run("Invalid Date", func(t *testing.T) {
+		importer := Statement{Profile: Profile{
+			Account: "12345", Date: 1, Payee: 2, Amount: 3,
+		}}
+		in := strings.NewReader(`01/10/2016,Pizza,-10.00`)
+		_, err := importer.Import(in)
+		if err == nil {
+			t.Error("wants error, got none")
+		}
+	})
+
+	t.Run("Header Names", func(t *testing.T) {
+		importer := Statement{Profile: Profile{
+			Account: "12345", Date: 1, Payee: 2, Amount: 3,
+			Headers: []string{"Date", "Payee", "Amount"},
+		}}
+		in := strings.NewReader("Date,Payee,Amount\n2016-10-01,Pizza,-10.00\n")
+		got, err := importer.Import(in)
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+		if n := len(got[0].Transactions); n != 1 {
+			t.Errorf("wants header row to be skipped, got %d transactions", n)
+		}
+	})
+
+	t.Run("Single Amount Column", func(t *testing.T) {
+		importer := Statement{Profile: Profile{
+			Account:     "12345",
+			AccountType: waukeen.Checking,
+			Currency:    "CAD",
+			Header:      true,
+			DateFormat:  "01/02/2006",
+			ID:          1,
+			Date:        2,
+			Payee:       3,
+			Memo:        4,
+			Amount:      5,
+		}}
+		in := strings.NewReader(`id,date,payee,memo,amount
+A1,10/01/2016,Dominos Pizza,Online,-25.50
+A2,10/03/2016,Payroll,,"1,200.00"
+`)
+		want := []waukeen.Statement{
+			{
+				Account: waukeen.Account{
+					Number:   "12345",
+					Type:     waukeen.Checking,
+					Currency: "CAD",
+				},
+				Transactions: []waukeen.Transaction{
+					{
+						FITID:       "A1",
+						Type:        waukeen.Debit,
+						Title:       "Dominos Pizza",
+						Description: "Online",
+						Amount:      -2550,
+						Date:        time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
+					},
+					{
+						FITID:  "A2",
+						Type:   waukeen.Credit,
+						Title:  "Payroll",
+						Amount: 120000,
+						Date:   time.Date(2016, 10, 3, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+		}
+		got, err := importer.Import(in)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("wants\n%+v\ngot\n%+v", want, got)
+		}
+	})
+
+	t.Run("Debit and Credit Columns", func(t *testing.T) {
+		importer := Statement{Profile: Profile{
+			Account:    "67890",
+			Comma:      ";",
+			DateFormat: "02.01.2006",
+			Decimal:    ",",
+			Date:       1,
+			Payee:      2,
+			Debit:      3,
+			Credit:     4,
+		}}
+		in := strings.NewReader(`01.10.2016;Supermarkt;1.234,56;
+02.10.2016;Gehalt;;2000
+`)
+		got, err := importer.Import(in)
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		trs := got[0].Transactions
+		if len(trs) != 2 {
+			t.Fatalf("wants 2 transactions, got %+v", trs)
+		}
+
+		if trs[0].Amount != -123456 || trs[0].Type != waukeen.Debit {
+			t.Errorf("wants debit of -123456, got %+v", trs[0])
+		}
+
+		if trs[1].Amount != 200000 || trs[1].Type != waukeen.Credit {
+			t.Errorf("wants credit of 200000, got %+v", trs[1])
+		}
+	})
+
+	t.Run("Synthetic FITIDs", func(t *testing.T) {
+		importer := Statement{Profile: Profile{
+			Account: "12345", Date: 1, Payee: 2, Amount: 3,
+		}}
+		file := `2016-10-01,Coffee,-2.00
+2016-10-01,Coffee,-2.00
+2016-10-02,Coffee,-2.00
+`
+		first, err := importer.Import(strings.NewReader(file))
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		second, err := importer.Import(strings.NewReader(file))
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("wants stable ids, got\n%+v\n%+v", first, second)
+		}
+
+		ids := make(map[string]bool)
+		for _, tr := range first[0].Transactions {
+			if tr.FITID == "" || ids[tr.FITID] {
+				t.Errorf("wants unique id, got %q", tr.FITID)
+			}
+			ids[tr.FITID] = true
+		}
+	})
+}
+
+func TestProfiles(t *testing.T) {
+	in := strings.NewReader(`[
+		{"name": "td", "account": "12345", "account_type": 1, "date": 1,
+		"payee": 2, "debit": 3, "credit": 4, "date_format": "01/02/2006",
+		"headers": ["Date", "Description", "Withdrawals", "Deposits"]}
+	]`)
+	want := []Profile{{
+		Name:        "td",
+		Account:     "12345",
+		AccountType: waukeen.Checking,
+		Date:        1,
+		Payee:       2,
+		Debit:       3,
+		Credit:      4,
+		DateFormat:  "01/02/2006",
+		Headers:     []string{"Date", "Description", "Withdrawals", "Deposits"},
+	}}
+	got, err := Profiles(in)
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants %+v, got %+v", want, got)
+	}
+}
+
+func TestProfileSniff(t *testing.T) {
+	p := Profile{Comma: ";", Headers: []string{"Date", "Payee", "Amount"}}
+
+	tests := []struct {
+		name string
+		head string
+		want bool
+	}{
+		{name: "header row", head: "Date;Payee;Amount\n2016-10-01;Pizza;-10.00\n", want: true},
+		{name: "case and spaces", head: "\ufeffdate; PAYEE ;amount\r\n", want: true},
+		{name: "other columns", head: "Date;Description;Amount\n", want: false},
+		{name: "extra column", head: "Date;Payee;Amount;Balance\n", want: false},
+		{name: "no header", head: "2016-10-01;Pizza;-10.00\n", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := p.Sniff([]byte(tt.head)); got != tt.want {
+			t.Errorf("%s: wants %t, got %t", tt.name, tt.want, got)
+		}
+	}
+
+	if (Profile{}).Sniff([]byte("Date,Payee,Amount\n")) {
+		t.Errorf("wants profile without headers to never match")
+	}
+}
+
+func Test_parseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		decimal string
+		want    int64
+	}{
+		{in: "", want: 0},
+		{in: "10", want: 1000},
+		{in: "-10.5", want: -1050},
+		{in: "$1,234.56", want: 123456},
+		{in: "(12.00)", want: -1200},
+		{in: "12.00-", want: -1200},
+		{in: "1.234,56", decimal: ",", want: 123456},
+		{in: "-0,99", decimal: ",", want: -99},
+		{in: "$-9.99", want: -999},
+		{in: "-$9.99", want: -999},
+		{in: "$ 9.99", want: 999},
+		{in: "€1.234,56-", decimal: ",", want: -123456},
+		{in: "+.5", want: 50},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in, tt.decimal)
+		if err != nil {
+			t.Errorf("parseAmount(%q) wants no error, got %s", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("parseAmount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"1.234", "abc", "1.2.3", "12a", "-", "$", "9.99 CAD", "-$-9.99"} {
+		_, err := parseAmount(in, "")
+		if err == nil {
+			t.Errorf("parseAmount(%q) wants error, got none", in)
+		}
+	}
+}
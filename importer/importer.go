@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/pkg/errors"
+)
+
+// Format describes a named statement importer and how to recognize its
+// files. Sniff inspects the first bytes of a file and takes precedence over
+// the file extension.
+type Format struct {
+	Name       string
+	Extensions []string
+	Sniff      func(head []byte) bool
+	Importer   waukeen.StatementsImporter
+}
+
+type Registry struct {
+	formats []Format
+}
+
+func New(formats ...Format) *Registry {
+	r := &Registry{}
+	for _, f := range formats {
+		r.Register(f)
+	}
+	return r
+}
+
+func (r *Registry) Register(f Format) {
+	for i, e := range f.Extensions {
+		f.Extensions[i] = strings.ToLower(e)
+	}
+	r.formats = append(r.formats, f)
+}
+
+func (r *Registry) Formats() []string {
+	names := make([]string, len(r.formats))
+	for i, f := range r.formats {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Find returns the importer for format, or detects it from the file when
+// format is empty, along with the name of the chosen format.
+func (r *Registry) Find(format, filename string,
+	head []byte) (string, waukeen.StatementsImporter, error) {
+	if format != "" {
+		for _, f := range r.formats {
+			if f.Name == format {
+				return f.Name, f.Importer, nil
+			}
+		}
+		return "", nil, errors.Errorf("unknown statement format %s", format)
+	}
+
+	var sniffed []Format
+	for _, f := range r.formats {
+		if f.Sniff != nil && f.Sniff(head) {
+			sniffed = append(sniffed, f)
+		}
+	}
+
+	if len(sniffed) == 1 {
+		return sniffed[0].Name, sniffed[0].Importer, nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	var matched []Format
+	for _, f := range r.formats {
+		for _, e := range f.Extensions {
+			if e == ext {
+				matched = append(matched, f)
+				break
+			}
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return "", nil, errors.Errorf("unable to detect format of %s", filename)
+	case 1:
+		return matched[0].Name, matched[0].Importer, nil
+	}
+
+	var names []string
+	for _, f := range matched {
+		names = append(names, f.Name)
+	}
+
+	return "", nil, errors.Errorf("%s could be any of %s, choose a format",
+		filename, strings.Join(names, ", "))
+}
+
+func SniffOFX(head []byte) bool {
+	head = bytes.ToUpper(head)
+	return bytes.Contains(head, []byte("OFXHEADER")) ||
+		bytes.Contains(head, []byte("<OFX>"))
+}
+
+func SniffQIF(head []byte) bool {
+	head = bytes.TrimLeft(head, "\ufeff \t\r\n")
+	for _, h := range []string{"!type:", "!account", "!option:", "!clear:"} {
+		if bytes.HasPrefix(bytes.ToLower(head), []byte(h)) {
+			return true
+		}
+	}
+	return false
+}
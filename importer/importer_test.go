@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/mock"
+)
+
+func TestRegistryInterface(t *testing.T) {
+	var _ waukeen.StatementsImporters = &Registry{}
+}
+
+func TestFind(t *testing.T) {
+	ofx := &mock.StatementsImporter{}
+	qif := &mock.StatementsImporter{}
+	bank := &mock.StatementsImporter{}
+	card := &mock.StatementsImporter{}
+
+	registry := New(
+		Format{Name: "ofx", Extensions: []string{".ofx", ".QFX"}, Sniff: SniffOFX, Importer: ofx},
+		Format{Name: "qif", Extensions: []string{".qif"}, Sniff: SniffQIF, Importer: qif},
+		Format{Name: "csv:bank", Extensions: []string{".csv"}, Importer: bank},
+	)
+
+	testCases := []struct {
+		name     string
+		format   string
+		filename string
+		head     string
+		want     waukeen.StatementsImporter
+		err      bool
+	}{
+		{name: "ofx content", filename: "export.txt", head: "OFXHEADER:100\n", want: ofx},
+		{name: "ofx xml content", filename: "export", head: "<?xml?>\n<ofx>", want: ofx},
+		{name: "qif content", filename: "export.txt", head: "!Type:Bank\n", want: qif},
+		{name: "qif with bom", filename: "export.txt", head: "\ufeff!Account\n", want: qif},
+		{name: "extension", filename: "visa.qfx", head: "", want: ofx},
+		{name: "csv extension", filename: "bank.CSV", head: "date,amount", want: bank},
+		{name: "unknown", filename: "bank.pdf", head: "%PDF", err: true},
+		{name: "override", format: "qif", filename: "bank.csv", want: qif},
+		{name: "invalid override", format: "xls", filename: "bank.csv", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, got, err := registry.Find(tc.format, tc.filename, []byte(tc.head))
+			if tc.err {
+				if err == nil {
+					t.Errorf("wants error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("wants no error, got %s", err)
+			}
+			if got != tc.want {
+				t.Errorf("wants %p importer, got %p", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("Ambiguous Extension", func(t *testing.T) {
+		registry.Register(Format{Name: "csv:card", Extensions: []string{".csv"}, Importer: card})
+		_, _, err := registry.Find("", "bank.csv", nil)
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+
+	t.Run("Sniffed Among Extensions", func(t *testing.T) {
+		sniff := func(head []byte) bool {
+			return strings.HasPrefix(string(head), "Posted,Merchant")
+		}
+		visa := &mock.StatementsImporter{}
+		registry.Register(Format{Name: "csv:visa", Extensions: []string{".csv"}, Sniff: sniff, Importer: visa})
+
+		name, got, err := registry.Find("", "visa.csv", []byte("Posted,Merchant,Amount\n"))
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if got != visa || name != "csv:visa" {
+			t.Errorf("wants csv:visa importer, got %s %p", name, got)
+		}
+	})
+}
+
+func TestFormats(t *testing.T) {
+	importer := &mock.StatementsImporter{}
+	registry := New(
+		Format{Name: "ofx", Importer: importer},
+		Format{Name: "qif", Importer: importer},
+	)
+
+	got := registry.Formats()
+	if len(got) != 2 || got[0] != "ofx" || got[1] != "qif" {
+		t.Errorf("wants [ofx qif], got %v", got)
+	}
+}
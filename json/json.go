@@ -2,6 +2,7 @@ package json
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 
 	"github.com/luizbranco/waukeen"
@@ -13,5 +14,31 @@ func (Rules) Import(in io.Reader) ([]waukeen.Rule, error) {
 	var rules []waukeen.Rule
 	dec := json.NewDecoder(in)
 	err := dec.Decode(&rules)
-	return rules, err
+	if err != nil {
+		return nil, err
+	}
+
+	for i, r := range rules {
+		err = r.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %s", i+1, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// Export writes rules as an indented JSON array. IDs and priorities are left
+// out, the position in the array keeps the order on import.
+func (Rules) Export(out io.Writer, rules []waukeen.Rule) error {
+	list := make([]waukeen.Rule, len(rules))
+	for i, r := range rules {
+		r.ID = ""
+		r.Priority = 0
+		list[i] = r
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
 }
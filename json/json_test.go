@@ -1,9 +1,11 @@
 package json
 
 import (
+	"bytes"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/luizbranco/waukeen"
 )
@@ -19,14 +21,32 @@ func TestRulesImport(t *testing.T) {
 		}
 	})
 
+	t.Run("Invalid Rule", func(t *testing.T) {
+		in := strings.NewReader(`[
+			{"type": "tag", "match": "dominos", "result": "pizza"},
+			{"type": "tag", "mode": "regex", "match": "(uber", "result": "transportation"}
+		]`)
+		_, err := importer.Import(in)
+		if err == nil {
+			t.Error("wants error, got none")
+		}
+	})
+
 	t.Run("Valid JSON", func(t *testing.T) {
 		in := strings.NewReader(`[
 			{"type": "tag", "match": "dominos", "result": "pizza"},
-			{"type": "replace", "match": "toronto", "result": "local"}
+			{"type": "replace", "match": "toronto", "result": "local"},
+			{"type": "tag", "mode": "prefix", "field": "description", "match": "uber", "result": "transportation"},
+			{"type": "tag", "match": "amazon", "result": "electronics",
+			 "conditions": {"min_amount": 50000, "transaction_type": 2}}
 		]`)
 		want := []waukeen.Rule{
 			{Type: waukeen.TagRule, Match: "dominos", Result: "pizza"},
 			{Type: waukeen.ReplaceRule, Match: "toronto", Result: "local"},
+			{Type: waukeen.TagRule, Mode: waukeen.PrefixMatch,
+				Field: waukeen.DescriptionField, Match: "uber", Result: "transportation"},
+			{Type: waukeen.TagRule, Match: "amazon", Result: "electronics",
+				Conditions: &waukeen.RuleConditions{MinAmount: 50000, TransactionType: waukeen.Debit}},
 		}
 		got, err := importer.Import(in)
 		if err != nil {
@@ -38,3 +58,36 @@ func TestRulesImport(t *testing.T) {
 		}
 	})
 }
+
+func TestRulesExport(t *testing.T) {
+	rules := []waukeen.Rule{
+		{ID: "1", Type: waukeen.ReplaceRule, Match: "toronto", Result: "local", Priority: 1},
+		{ID: "2", Type: waukeen.TagRule, Mode: waukeen.RegexMatch, Field: waukeen.DescriptionField,
+			Match: "^uber", Result: "transportation", Priority: 2, Stop: true},
+		{ID: "3", Type: waukeen.TagRule, Match: "amazon", Result: "electronics", Priority: 3,
+			Conditions: &waukeen.RuleConditions{MinAmount: 50000, Weekdays: []time.Weekday{time.Sunday}}},
+	}
+
+	buf := &bytes.Buffer{}
+	err := Rules{}.Export(buf, rules)
+	if err != nil {
+		t.Fatalf("wants no error, got %s", err)
+	}
+
+	if strings.Contains(buf.String(), `"id"`) || strings.Contains(buf.String(), `"priority"`) {
+		t.Errorf("wants ids and priorities left out, got %s", buf)
+	}
+
+	got, err := Rules{}.Import(buf)
+	if err != nil {
+		t.Fatalf("wants no error, got %s", err)
+	}
+
+	for i := range rules {
+		rules[i].ID = ""
+		rules[i].Priority = 0
+	}
+	if !reflect.DeepEqual(rules, got) {
+		t.Errorf("wants %+v, got %+v", rules, got)
+	}
+}
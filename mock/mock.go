@@ -2,6 +2,7 @@ package mock
 
 import (
 	"io"
+	"time"
 
 	"github.com/luizbranco/waukeen"
 	"github.com/luizbranco/waukeen/web"
@@ -23,6 +24,14 @@ func (m *RulesImporter) Import(in io.Reader) ([]waukeen.Rule, error) {
 	return m.ImportMethod(in)
 }
 
+type RulesExporter struct {
+	ExportMethod func(io.Writer, []waukeen.Rule) error
+}
+
+func (m *RulesExporter) Export(out io.Writer, rules []waukeen.Rule) error {
+	return m.ExportMethod(out, rules)
+}
+
 type StatementsImporter struct {
 	ImportMethod func(io.Reader) ([]waukeen.Statement, error)
 }
@@ -31,21 +40,60 @@ func (m *StatementsImporter) Import(in io.Reader) ([]waukeen.Statement, error) {
 	return m.ImportMethod(in)
 }
 
+type AccountStatementsImporter struct {
+	StatementsImporter
+	WithAccountMethod func(number string) waukeen.StatementsImporter
+}
+
+func (m *AccountStatementsImporter) WithAccount(number string) waukeen.StatementsImporter {
+	return m.WithAccountMethod(number)
+}
+
+type StatementsImporters struct {
+	FormatsMethod func() []string
+	FindMethod    func(format, filename string, head []byte) (string, waukeen.StatementsImporter, error)
+}
+
+func (m *StatementsImporters) Formats() []string {
+	return m.FormatsMethod()
+}
+
+func (m *StatementsImporters) Find(format, filename string,
+	head []byte) (string, waukeen.StatementsImporter, error) {
+	return m.FindMethod(format, filename, head)
+}
+
 type TransactionTransformer struct {
-	TransformMethod func(*waukeen.Transaction, waukeen.Rule)
+	TransformMethod func(*waukeen.Transaction, waukeen.Rule) bool
 }
 
-func (m *TransactionTransformer) Transform(t *waukeen.Transaction, r waukeen.Rule) {
-	m.TransformMethod(t, r)
+func (m *TransactionTransformer) Transform(t *waukeen.Transaction, r waukeen.Rule) bool {
+	return m.TransformMethod(t, r)
 }
 
 type BudgetCalculator struct {
-	CalculateMethod func(int, []waukeen.Transaction, []waukeen.Tag) []waukeen.Budget
+	CalculateMethod func(time.Time, int, []waukeen.Transaction, []waukeen.Tag) []waukeen.Budget
+	TotalsMethod    func(time.Time, int, []waukeen.Transaction, []waukeen.Tag) []waukeen.BudgetTotals
+}
+
+func (m *BudgetCalculator) Calculate(start time.Time, months int,
+	trs []waukeen.Transaction, tags []waukeen.Tag) []waukeen.Budget {
+	return m.CalculateMethod(start, months, trs, tags)
+}
+
+func (m *BudgetCalculator) Totals(start time.Time, months int,
+	trs []waukeen.Transaction, tags []waukeen.Tag) []waukeen.BudgetTotals {
+	return m.TotalsMethod(start, months, trs, tags)
+}
+
+type EnvelopeCalculator struct {
+	EnvelopesMethod func(time.Time, []waukeen.Transaction, []waukeen.Tag,
+		waukeen.EnvelopeLedger) waukeen.EnvelopeMonth
 }
 
-func (m *BudgetCalculator) Calculate(months int, trs []waukeen.Transaction,
-	tags []waukeen.Tag) []waukeen.Budget {
-	return m.CalculateMethod(months, trs, tags)
+func (m *EnvelopeCalculator) Envelopes(month time.Time, trs []waukeen.Transaction,
+	tags []waukeen.Tag, l waukeen.EnvelopeLedger) waukeen.EnvelopeMonth {
+	return m.EnvelopesMethod(month, trs, tags, l)
 }
 
 type Database struct {
@@ -61,9 +109,11 @@ type Database struct {
 	FindTransactionsMethod  func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error)
 	FindTransactionMethod   func(string) (*waukeen.Transaction, error)
 
-	CreateRuleMethod func(*waukeen.Rule) error
-	DeleteRuleMethod func(string) error
-	FindRulesMethod  func(ids ...string) ([]waukeen.Rule, error)
+	CreateRuleMethod   func(*waukeen.Rule) error
+	UpdateRuleMethod   func(*waukeen.Rule) error
+	DeleteRuleMethod   func(string) error
+	FindRulesMethod    func(ids ...string) ([]waukeen.Rule, error)
+	ReorderRulesMethod func(ids []string) error
 
 	AllTagsMethod   func() ([]waukeen.Tag, error)
 	CreateTagMethod func(*waukeen.Tag) error
@@ -73,6 +123,22 @@ type Database struct {
 	FindTagsMethod  func(starts string) ([]waukeen.Tag, error)
 
 	CreateStatementMethod func(waukeen.Statement, waukeen.TransactionTransformer) error
+
+	FindImportsMethod  func() ([]waukeen.Import, error)
+	DeleteImportMethod func(string) error
+
+	CreateTransactionEditMethod func(*waukeen.TransactionEdit) error
+	FindTransactionEditsMethod  func() ([]waukeen.TransactionEdit, error)
+
+	FindTagModelMethod func() (*waukeen.TagModel, error)
+	SaveTagModelMethod func(*waukeen.TagModel) error
+
+	SetEnvelopeAssignmentMethod func(*waukeen.EnvelopeAssignment) error
+	CreateEnvelopeMoveMethod    func(*waukeen.EnvelopeMove) error
+	DeleteEnvelopeMoveMethod    func(string) error
+	FindEnvelopeLedgerMethod    func() (*waukeen.EnvelopeLedger, error)
+
+	WithTxMethod func(func(waukeen.Database) error) error
 }
 
 func (m *Database) CreateAccount(a *waukeen.Account) error {
@@ -119,6 +185,10 @@ func (m *Database) CreateRule(r *waukeen.Rule) error {
 	return m.CreateRuleMethod(r)
 }
 
+func (m *Database) UpdateRule(r *waukeen.Rule) error {
+	return m.UpdateRuleMethod(r)
+}
+
 func (m *Database) DeleteRule(id string) error {
 	return m.DeleteRuleMethod(id)
 }
@@ -127,6 +197,10 @@ func (m *Database) FindRules(ids ...string) ([]waukeen.Rule, error) {
 	return m.FindRulesMethod(ids...)
 }
 
+func (m *Database) ReorderRules(ids []string) error {
+	return m.ReorderRulesMethod(ids)
+}
+
 func (m *Database) CreateStatement(s waukeen.Statement, t waukeen.TransactionTransformer) error {
 	return m.CreateStatementMethod(s, t)
 }
@@ -154,3 +228,47 @@ func (m *Database) FindTags(starts string) ([]waukeen.Tag, error) {
 func (m *Database) DeleteTag(id string) error {
 	return m.DeleteTagMethod(id)
 }
+
+func (m *Database) FindImports() ([]waukeen.Import, error) {
+	return m.FindImportsMethod()
+}
+
+func (m *Database) DeleteImport(id string) error {
+	return m.DeleteImportMethod(id)
+}
+
+func (m *Database) CreateTransactionEdit(e *waukeen.TransactionEdit) error {
+	return m.CreateTransactionEditMethod(e)
+}
+
+func (m *Database) FindTransactionEdits() ([]waukeen.TransactionEdit, error) {
+	return m.FindTransactionEditsMethod()
+}
+
+func (m *Database) FindTagModel() (*waukeen.TagModel, error) {
+	return m.FindTagModelMethod()
+}
+
+func (m *Database) SaveTagModel(tm *waukeen.TagModel) error {
+	return m.SaveTagModelMethod(tm)
+}
+
+func (m *Database) SetEnvelopeAssignment(a *waukeen.EnvelopeAssignment) error {
+	return m.SetEnvelopeAssignmentMethod(a)
+}
+
+func (m *Database) CreateEnvelopeMove(mv *waukeen.EnvelopeMove) error {
+	return m.CreateEnvelopeMoveMethod(mv)
+}
+
+func (m *Database) DeleteEnvelopeMove(id string) error {
+	return m.DeleteEnvelopeMoveMethod(id)
+}
+
+func (m *Database) FindEnvelopeLedger() (*waukeen.EnvelopeLedger, error) {
+	return m.FindEnvelopeLedgerMethod()
+}
+
+func (m *Database) WithTx(fn func(waukeen.Database) error) error {
+	return m.WithTxMethod(fn)
+}
@@ -11,6 +11,7 @@ func TestMockSatisfiesInterfaces(t *testing.T) {
 	var _ web.Template = &Template{}
 	var _ waukeen.RulesImporter = &RulesImporter{}
 	var _ waukeen.StatementsImporter = &StatementsImporter{}
+	var _ waukeen.StatementsImporters = &StatementsImporters{}
 	var _ waukeen.TransactionTransformer = &TransactionTransformer{}
 	var _ waukeen.Database = &Database{}
 	var _ waukeen.BudgetCalculator = &BudgetCalculator{}
@@ -0,0 +1,313 @@
+package qif
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/pkg/errors"
+)
+
+// Statement imports QIF files. QIF doesn't carry account numbers, so the
+// name of the !Account block is used instead, falling back to Account when
+// the file only has transaction blocks.
+type Statement struct {
+	Account  string
+	Currency string
+	DayFirst bool
+}
+
+// WithAccount uses the account number for files without an !Account block.
+func (s Statement) WithAccount(number string) waukeen.StatementsImporter {
+	s.Account = number
+	return s
+}
+
+type section int
+
+const (
+	noSection section = iota
+	accountSection
+	transactionSection
+	skipSection
+)
+
+func (s Statement) Import(in io.Reader) ([]waukeen.Statement, error) {
+	var stmts []waukeen.Statement
+	var current *waukeen.Statement
+	var sec section
+
+	account := waukeen.Account{Number: s.Account, Currency: s.Currency}
+	seen := make(map[string]int)
+	fields := make(map[byte][]string)
+
+	scanner := bufio.NewScanner(in)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if text[0] == '!' {
+			header := strings.ToLower(strings.TrimSpace(text[1:]))
+			switch {
+			case header == "account":
+				sec = accountSection
+			case strings.HasPrefix(header, "type:"):
+				typ, ok := accountType(header[len("type:"):])
+				if !ok {
+					sec = skipSection
+					continue
+				}
+				sec = transactionSection
+				if account.Number == "" {
+					return nil, errors.Errorf("qif line %d: missing account", line)
+				}
+				acc := account
+				if acc.Type == waukeen.OtherAccount {
+					acc.Type = typ
+				}
+				stmts = append(stmts, waukeen.Statement{Account: acc})
+				current = &stmts[len(stmts)-1]
+			default:
+				// options such as !Option:AutoSwitch don't open a new block
+			}
+			fields = make(map[byte][]string)
+			continue
+		}
+
+		if text[0] != '^' {
+			fields[text[0]] = append(fields[text[0]], strings.TrimSpace(text[1:]))
+			continue
+		}
+
+		switch sec {
+		case accountSection:
+			account = s.account(fields)
+		case transactionSection:
+			t, err := s.transaction(fields)
+			if err != nil {
+				return nil, errors.Wrapf(err, "qif line %d", line)
+			}
+			id := hash(current.Account.Number, fields)
+			seen[id]++
+			t.FITID = fmt.Sprintf("%s-%d", id, seen[id])
+			current.Transactions = append(current.Transactions, t)
+		}
+
+		fields = make(map[byte][]string)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read qif")
+	}
+
+	if len(stmts) == 0 {
+		return nil, errors.New("qif file has no transactions")
+	}
+
+	return stmts, nil
+}
+
+func (s Statement) account(fields map[byte][]string) waukeen.Account {
+	acc := waukeen.Account{
+		Number:   first(fields, 'N'),
+		Name:     first(fields, 'N'),
+		Currency: s.Currency,
+	}
+
+	if acc.Number == "" {
+		acc.Number = s.Account
+	}
+
+	acc.Type, _ = accountType(first(fields, 'T'))
+
+	if b := first(fields, '$'); b != "" {
+		acc.Balance, _ = parseAmount(b)
+	}
+
+	return acc
+}
+
+func (s Statement) transaction(fields map[byte][]string) (waukeen.Transaction, error) {
+	t := waukeen.Transaction{
+		Title:       first(fields, 'P'),
+		Description: first(fields, 'M'),
+	}
+
+	date, err := s.parseDate(first(fields, 'D'))
+	if err != nil {
+		return t, err
+	}
+	t.Date = date
+
+	amount := first(fields, 'T')
+	if amount == "" {
+		amount = first(fields, 'U')
+	}
+	t.Amount, err = parseAmount(amount)
+	if err != nil {
+		return t, err
+	}
+
+	if t.Title == "" {
+		t.Title = t.Description
+	}
+
+	_, err = strconv.Atoi(first(fields, 'N'))
+	switch {
+	case err == nil:
+		t.Type = waukeen.Check
+	case t.Amount < 0:
+		t.Type = waukeen.Debit
+	default:
+		t.Type = waukeen.Credit
+	}
+
+	for _, f := range []byte{'L', 'S'} {
+		for _, c := range fields[f] {
+			t.AddTags(category(c)...)
+		}
+	}
+
+	return t, nil
+}
+
+// category converts a QIF category such as "Food:Restaurants/Work" into tags.
+// Transfers between accounts are written as "[Account]" and aren't tags.
+func category(c string) []string {
+	if i := strings.Index(c, "/"); i >= 0 {
+		c = c[:i]
+	}
+
+	if strings.HasPrefix(c, "[") {
+		return nil
+	}
+
+	var tags []string
+	for _, name := range strings.Split(c, ":") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+func accountType(t string) (waukeen.AccountType, bool) {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "bank":
+		return waukeen.Checking, true
+	case "ccard":
+		return waukeen.CreditCard, true
+	case "cash", "oth a", "oth l":
+		return waukeen.OtherAccount, true
+	}
+	return waukeen.OtherAccount, false
+}
+
+// parseDate understands the usual QIF variations: 10/01/2016, 10/1'16 and
+// 10/ 1/16, swapping day and month when DayFirst is set.
+func (s Statement) parseDate(d string) (time.Time, error) {
+	parts := strings.FieldsFunc(d, func(r rune) bool {
+		return r == '/' || r == '\'' || r == '-' || r == '.'
+	})
+
+	if len(parts) != 3 {
+		return time.Time{}, errors.Errorf("invalid date %q", d)
+	}
+
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return time.Time{}, errors.Errorf("invalid date %q", d)
+		}
+		nums[i] = n
+	}
+
+	month, day, year := nums[0], nums[1], nums[2]
+	if s.DayFirst {
+		month, day = day, month
+	}
+
+	if year < 100 {
+		if strings.Contains(d, "'") || year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, errors.Errorf("invalid date %q", d)
+	}
+
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
+}
+
+func parseAmount(s string) (int64, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", "", -1)
+	if s == "" {
+		return 0, errors.New("missing amount")
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+
+	units, cents := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		units, cents = s[:i], s[i+1:]
+	}
+
+	switch len(cents) {
+	case 0:
+		cents = "00"
+	case 1:
+		cents += "0"
+	case 2:
+	default:
+		return 0, errors.Errorf("invalid amount %q", s)
+	}
+
+	if units == "" {
+		units = "0"
+	}
+
+	n, err := strconv.ParseInt(units+cents, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid amount %q", s)
+	}
+
+	if negative {
+		n *= -1
+	}
+
+	return n, nil
+}
+
+// hash fingerprints a record, QIF has no transaction ids and re-importing an
+// overlapping export must not duplicate entries.
+func hash(account string, fields map[byte][]string) string {
+	values := []string{account}
+	for _, f := range []byte{'D', 'T', 'U', 'P', 'M', 'N', 'L'} {
+		values = append(values, strings.Join(fields[f], ","))
+	}
+	sum := sha1.Sum([]byte(strings.Join(values, "\x00")))
+	return fmt.Sprintf("%x", sum[:10])
+}
+
+func first(fields map[byte][]string, f byte) string {
+	if v := fields[f]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
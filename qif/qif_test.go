@@ -0,0 +1,249 @@
+package qif
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luizbranco/waukeen"
+)
+
+func TestStatementInterface(t *testing.T) {
+	var _ waukeen.StatementsImporter = Statement{}
+	var _ waukeen.AccountStatementsImporter = Statement{}
+}
+
+func TestStatementsImport(t *testing.T) {
+	t.Run("Empty File", func(t *testing.T) {
+		importer := Statement{Account: "12345"}
+		_, err := importer.Import(strings.NewReader(``))
+		if err == nil {
+			t.Error("wants error, got none")
+		}
+	})
+
+	t.Run("Missing Account", func(t *testing.T) {
+		importer := Statement{}
+		in := strings.NewReader("!Type:Bank\nD10/01/2016\nT-10.00\nPPizza\n^\n")
+		_, err := importer.Import(in)
+		if err == nil {
+			t.Error("wants error, got none")
+		}
+	})
+
+	t.Run("With Account", func(t *testing.T) {
+		importer := Statement{Currency: "CAD"}.WithAccount("12345")
+		in := strings.NewReader("!Type:Bank\nD10/01/2016\nT-10.00\nPPizza\n^\n")
+		got, err := importer.Import(in)
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		acc := waukeen.Account{Number: "12345", Type: waukeen.Checking, Currency: "CAD"}
+		if len(got) != 1 || !reflect.DeepEqual(acc, got[0].Account) {
+			t.Errorf("wants account %+v, got %+v", acc, got)
+		}
+	})
+
+	t.Run("Invalid Date", func(t *testing.T) {
+		importer := Statement{Account: "12345"}
+		in := strings.NewReader("!Type:Bank\nD2016\nT-10.00\nPPizza\n^\n")
+		_, err := importer.Import(in)
+		if err == nil {
+			t.Error("wants error, got none")
+		}
+	})
+
+	t.Run("Invalid Amount", func(t *testing.T) {
+		importer := Statement{Account: "12345"}
+		in := strings.NewReader("!Type:Bank\nD10/01/2016\nT-12.345\nPPizza\n^\n")
+		_, err := importer.Import(in)
+		if err == nil {
+			t.Error("wants error, got none")
+		}
+	})
+
+	t.Run("Bank Transactions", func(t *testing.T) {
+		importer := Statement{Account: "12345", Currency: "CAD"}
+		in := strings.NewReader(`!Type:Bank
+D10/01/2016
+T-1,025.50
+PDominos Pizza
+MOnline order
+LFood:Restaurants
+^
+D10/ 3'16
+T2000.00
+PPayroll
+LSalary
+^
+D10/05/2016
+T-50.00
+N1024
+PLandlord
+L[Savings]
+^
+`)
+		got, err := importer.Import(in)
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		if len(got) != 1 {
+			t.Fatalf("wants 1 statement, got %+v", got)
+		}
+
+		acc := waukeen.Account{Number: "12345", Type: waukeen.Checking, Currency: "CAD"}
+		if !reflect.DeepEqual(acc, got[0].Account) {
+			t.Errorf("wants %+v, got %+v", acc, got[0].Account)
+		}
+
+		trs := got[0].Transactions
+		for i := range trs {
+			if trs[i].FITID == "" {
+				t.Errorf("wants synthetic fitid, got none")
+			}
+			trs[i].FITID = ""
+		}
+
+		want := []waukeen.Transaction{
+			{
+				Type:        waukeen.Debit,
+				Title:       "Dominos Pizza",
+				Description: "Online order",
+				Amount:      -102550,
+				Date:        time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
+				Tags:        []string{"food", "restaurants"},
+			},
+			{
+				Type:   waukeen.Credit,
+				Title:  "Payroll",
+				Amount: 200000,
+				Date:   time.Date(2016, 10, 3, 0, 0, 0, 0, time.UTC),
+				Tags:   []string{"salary"},
+			},
+			{
+				Type:   waukeen.Check,
+				Title:  "Landlord",
+				Amount: -5000,
+				Date:   time.Date(2016, 10, 5, 0, 0, 0, 0, time.UTC),
+			},
+		}
+
+		if !reflect.DeepEqual(want, trs) {
+			t.Errorf("wants\n%+v\ngot\n%+v", want, trs)
+		}
+	})
+
+	t.Run("Account Blocks", func(t *testing.T) {
+		importer := Statement{DayFirst: true}
+		in := strings.NewReader(`!Option:AutoSwitch
+!Account
+NVisa
+TCCard
+$-436.14
+^
+!Type:CCard
+D09/08/2016
+T-163.25
+PGame of Thrones
+^
+!Account
+NEveryday
+TBank
+^
+!Type:Bank
+D10/09/2016
+T49.77
+PCANADA
+^
+!Type:Invst
+D10/09/2016
+NBuy
+^
+`)
+		got, err := importer.Import(in)
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		if len(got) != 2 {
+			t.Fatalf("wants 2 statements, got %+v", got)
+		}
+
+		card := waukeen.Account{
+			Number:  "Visa",
+			Name:    "Visa",
+			Type:    waukeen.CreditCard,
+			Balance: -43614,
+		}
+		if !reflect.DeepEqual(card, got[0].Account) {
+			t.Errorf("wants %+v, got %+v", card, got[0].Account)
+		}
+
+		date := time.Date(2016, 8, 9, 0, 0, 0, 0, time.UTC)
+		if tr := got[0].Transactions[0]; !tr.Date.Equal(date) {
+			t.Errorf("wants date %s, got %s", date, tr.Date)
+		}
+
+		if got[1].Account.Type != waukeen.Checking {
+			t.Errorf("wants checking account, got %s", got[1].Account.Type)
+		}
+
+		if len(got[1].Transactions) != 1 {
+			t.Errorf("wants investment block to be skipped, got %+v", got[1].Transactions)
+		}
+	})
+
+	t.Run("Stable FITIDs", func(t *testing.T) {
+		importer := Statement{Account: "12345"}
+		file := "!Type:Bank\nD10/01/2016\nT-2.00\nPCoffee\n^\nD10/01/2016\nT-2.00\nPCoffee\n^\n"
+
+		first, err := importer.Import(strings.NewReader(file))
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+		second, err := importer.Import(strings.NewReader(file))
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("wants stable ids, got\n%+v\n%+v", first, second)
+		}
+
+		trs := first[0].Transactions
+		if trs[0].FITID == trs[1].FITID {
+			t.Errorf("wants unique ids, got %s twice", trs[0].FITID)
+		}
+	})
+}
+
+func Test_parseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{in: "10", want: 1000},
+		{in: "-10.5", want: -1050},
+		{in: "1,234.56", want: 123456},
+		{in: ".99", want: 99},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in)
+		if err != nil {
+			t.Errorf("parseAmount(%q) wants no error, got %s", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("parseAmount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"12.345", "", "abc"} {
+		_, err := parseAmount(in)
+		if err == nil {
+			t.Errorf("parseAmount(%q) wants error, got none", in)
+		}
+	}
+}
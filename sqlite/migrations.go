@@ -0,0 +1,519 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Migration is a numbered schema change and when it was applied, AppliedAt
+// is zero for pending migrations.
+type Migration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+type migration struct {
+	version int
+	name    string
+	up      func(*sql.Tx) error
+}
+
+// migrations must only ever be appended to, a released version is never
+// edited since databases in the wild already applied it.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial schema",
+		up: execAll(
+			`
+			CREATE TABLE IF NOT EXISTS accounts(
+				id INTEGER PRIMARY KEY,
+				number TEXT NOT NULL CHECK(number <> ''),
+				name TEXT,
+				type INTEGER NOT NULL,
+				currency TEXT,
+				balance INTEGER
+			);
+			`,
+			`
+			CREATE TABLE IF NOT EXISTS transactions(
+				id INTEGER PRIMARY KEY,
+				account_id INTEGER NOT NULL,
+				fitid TEXT NOT NULL CHECK(fitid <> ''),
+				type INTEGER NOT NULL,
+				title TEXT NOT NULL CHECK(title <> ''),
+				alias TEXT,
+				description TEXT,
+				amount INTEGER,
+				date DATETIME,
+				FOREIGN KEY(account_id) REFERENCES accounts(id) ON DELETE CASCADE
+			);
+			`,
+			`
+			CREATE UNIQUE INDEX IF NOT EXISTS account_fitid ON
+			transactions(account_id, fitid)
+			`,
+			`
+			CREATE TABLE IF NOT EXISTS tags(
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE CHECK(name <> ''),
+				monthly_budget INTEGER
+			);
+			`,
+			`
+			CREATE TABLE IF NOT EXISTS transaction_tags(
+				id INTEGER PRIMARY KEY,
+				transaction_id INTEGER NOT NULL,
+				tag_id INTEGER NOT NULL,
+				FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+				FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+			);
+			`,
+			`
+			CREATE UNIQUE INDEX IF NOT EXISTS transaction_tag ON
+			transaction_tags(transaction_id, tag_id)
+			`,
+			`
+			CREATE TABLE IF NOT EXISTS rules(
+				id INTEGER PRIMARY KEY,
+				type INTEGER NOT NULL,
+				match TEXT NOT NULL CHECK(match <> ''),
+				result TEXT NOT NULL
+			);
+			`,
+		),
+	},
+	{
+		version: 2,
+		name:    "statement imports",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS imports(
+				id INTEGER PRIMARY KEY,
+				filename TEXT,
+				importer TEXT,
+				account_id INTEGER NOT NULL,
+				date DATETIME,
+				created INTEGER,
+				skipped INTEGER,
+				FOREIGN KEY(account_id) REFERENCES accounts(id) ON DELETE CASCADE
+			);
+			`)
+			if err != nil {
+				return err
+			}
+			return addColumn(tx, "transactions", "import_id",
+				"INTEGER REFERENCES imports(id) ON DELETE CASCADE")
+		},
+	},
+	{
+		version: 3,
+		name:    "rule match mode and field",
+		up: func(tx *sql.Tx) error {
+			err := addColumn(tx, "rules", "mode", "INTEGER NOT NULL DEFAULT 0")
+			if err != nil {
+				return err
+			}
+			return addColumn(tx, "rules", "field", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 4,
+		name:    "rule conditions",
+		up: func(tx *sql.Tx) error {
+			columns := [][2]string{
+				{"min_amount", "INTEGER NOT NULL DEFAULT 0"},
+				{"max_amount", "INTEGER NOT NULL DEFAULT 0"},
+				{"accounts", "TEXT NOT NULL DEFAULT ''"},
+				{"transaction_type", "INTEGER NOT NULL DEFAULT 0"},
+				{"weekdays", "TEXT NOT NULL DEFAULT ''"},
+				{"first_day", "INTEGER NOT NULL DEFAULT 0"},
+				{"last_day", "INTEGER NOT NULL DEFAULT 0"},
+			}
+			for _, c := range columns {
+				err := addColumn(tx, "rules", c[0], c[1])
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 5,
+		name:    "rule priority",
+		up: func(tx *sql.Tx) error {
+			err := addColumn(tx, "rules", "priority", "INTEGER NOT NULL DEFAULT 0")
+			if err != nil {
+				return err
+			}
+			err = addColumn(tx, "rules", "stop", "INTEGER NOT NULL DEFAULT 0")
+			if err != nil {
+				return err
+			}
+			return prioritizeRules(tx)
+		},
+	},
+	{
+		version: 6,
+		name:    "transaction manual edits",
+		up: func(tx *sql.Tx) error {
+			err := addColumn(tx, "transactions", "alias_edited", "INTEGER NOT NULL DEFAULT 0")
+			if err != nil {
+				return err
+			}
+			return addColumn(tx, "transactions", "tags_edited", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 7,
+		name:    "tag model",
+		up: execAll(
+			`
+			CREATE TABLE tag_model_tags(
+				tag TEXT PRIMARY KEY,
+				transactions INTEGER NOT NULL
+			);
+			`,
+			`
+			CREATE TABLE tag_model_tokens(
+				tag TEXT NOT NULL,
+				token TEXT NOT NULL,
+				count INTEGER NOT NULL,
+				PRIMARY KEY(tag, token)
+			);
+			`,
+		),
+	},
+	{
+		version: 8,
+		name:    "transaction edits",
+		up: execAll(
+			`
+			CREATE TABLE transaction_edits(
+				id INTEGER PRIMARY KEY,
+				transaction_id INTEGER NOT NULL,
+				title TEXT NOT NULL,
+				alias TEXT NOT NULL DEFAULT '',
+				tag TEXT NOT NULL DEFAULT '',
+				date DATETIME NOT NULL,
+				FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
+			);
+			`,
+		),
+	},
+	{
+		version: 9,
+		name:    "tag budget rollover",
+		up: func(tx *sql.Tx) error {
+			err := addColumn(tx, "tags", "rollover", "INTEGER NOT NULL DEFAULT 0")
+			if err != nil {
+				return err
+			}
+			return addColumn(tx, "tags", "rollover_cap", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 10,
+		name:    "tag month budgets",
+		up: execAll(
+			`
+			CREATE TABLE budgets(
+				id INTEGER PRIMARY KEY,
+				tag_id INTEGER NOT NULL,
+				month DATETIME NOT NULL,
+				amount INTEGER NOT NULL,
+				UNIQUE(tag_id, month),
+				FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+			);
+			`,
+		),
+	},
+	{
+		version: 11,
+		name:    "envelopes",
+		up: execAll(
+			`
+			CREATE TABLE envelope_assignments(
+				id INTEGER PRIMARY KEY,
+				tag_id INTEGER NOT NULL,
+				month DATETIME NOT NULL,
+				amount INTEGER NOT NULL,
+				UNIQUE(tag_id, month),
+				FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+			);
+			`,
+			`
+			CREATE TABLE envelope_moves(
+				id INTEGER PRIMARY KEY,
+				from_tag_id INTEGER NOT NULL,
+				to_tag_id INTEGER NOT NULL,
+				month DATETIME NOT NULL,
+				amount INTEGER NOT NULL,
+				FOREIGN KEY(from_tag_id) REFERENCES tags(id) ON DELETE CASCADE,
+				FOREIGN KEY(to_tag_id) REFERENCES tags(id) ON DELETE CASCADE
+			);
+			`,
+		),
+	},
+	{
+		version: 12,
+		name:    "income tags",
+		up: func(tx *sql.Tx) error {
+			return addColumn(tx, "tags", "income", "INTEGER NOT NULL DEFAULT 0")
+		},
+	},
+	{
+		version: 13,
+		name:    "tag parents",
+		up: func(tx *sql.Tx) error {
+			return addColumn(tx, "tags", "parent_id",
+				"INTEGER REFERENCES tags(id) ON DELETE SET NULL")
+		},
+	},
+	{
+		version: 14,
+		name:    "rule account numbers",
+		up:      ruleAccountNumbers,
+	},
+}
+
+// prioritizeRules keeps the order existing rules were applied in, which used
+// to be alphabetical by match.
+func prioritizeRules(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id FROM rules ORDER BY match COLLATE NOCASE, id")
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		_, err = tx.Exec("UPDATE rules SET priority = ? WHERE id = ?", i+1, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ruleAccountNumbers replaces the account ids rule conditions used to have
+// by the account numbers, ids of accounts deleted since are kept.
+func ruleAccountNumbers(tx *sql.Tx) error {
+	numbers := make(map[string]string)
+
+	rows, err := tx.Query("SELECT id, number FROM accounts")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id, number string
+		err = rows.Scan(&id, &number)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		numbers[id] = number
+	}
+	rows.Close()
+
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	rows, err = tx.Query("SELECT id, accounts FROM rules WHERE accounts <> ''")
+	if err != nil {
+		return err
+	}
+
+	rules := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var accounts string
+		err = rows.Scan(&id, &accounts)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		rules[id] = accounts
+	}
+	rows.Close()
+
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	for id, accounts := range rules {
+		list := strings.Split(accounts, ",")
+		for i, a := range list {
+			if n, ok := numbers[a]; ok {
+				list[i] = n
+			}
+		}
+		_, err = tx.Exec("UPDATE rules SET accounts = ? WHERE id = ?",
+			strings.Join(list, ","), id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func execAll(queries ...string) func(*sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, q := range queries {
+			_, err := tx.Exec(q)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumn is idempotent so databases that got the column before it was
+// tracked by a migration can still be upgraded.
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notnull, pk int
+		var name, ctype string
+		var value sql.NullString
+
+		err = rows.Scan(&cid, &name, &ctype, &notnull, &value, &pk)
+		if err != nil {
+			return err
+		}
+
+		if name == column {
+			return nil
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	q := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	_, err = tx.Exec(q)
+	return err
+}
+
+func (db *DB) createMigrationsTable() error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	);
+	`)
+	return errors.Wrap(err, "create schema migrations table")
+}
+
+// Migrations lists every known migration in order, with the time it was
+// applied to this database.
+func (db *DB) Migrations() ([]Migration, error) {
+	err := db.createMigrationsTable()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, errors.Wrap(err, "query schema migrations")
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+
+	for rows.Next() {
+		var version int
+		var at time.Time
+		err = rows.Scan(&version, &at)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan schema migrations")
+		}
+		applied[version] = at
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "find schema migrations")
+	}
+
+	list := make([]Migration, len(migrations))
+	for i, m := range migrations {
+		list[i] = Migration{
+			Version:   m.version,
+			Name:      m.name,
+			AppliedAt: applied[m.version],
+		}
+	}
+
+	return list, nil
+}
+
+// Migrate applies every pending migration in order, each one in its own
+// transaction.
+func (db *DB) Migrate() error {
+	list, err := db.Migrations()
+	if err != nil {
+		return err
+	}
+
+	for i, m := range migrations {
+		if !list[i].AppliedAt.IsZero() {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return errors.Wrap(err, "begin migration")
+		}
+
+		err = m.up(tx)
+		if err == nil {
+			_, err = tx.Exec(`INSERT into schema_migrations (version, name,
+			applied_at) values (?, ?, ?)`, m.version, m.name, time.Now())
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "migration %d %s", m.version, m.name)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return errors.Wrapf(err, "commit migration %d", m.version)
+		}
+	}
+
+	return nil
+}
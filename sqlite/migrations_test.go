@@ -0,0 +1,175 @@
+package sqlite
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "waukeen_db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := tmpfile.Name()
+	defer os.Remove(path)
+
+	t.Run("Status", func(t *testing.T) {
+		db, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		list, err := db.Migrations()
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(list) != len(migrations) {
+			t.Fatalf("wants %d migrations, got %d", len(migrations), len(list))
+		}
+		for _, m := range list {
+			if !m.AppliedAt.IsZero() {
+				t.Errorf("wants migration %d pending, got applied", m.Version)
+			}
+		}
+	})
+
+	t.Run("Existing Data", func(t *testing.T) {
+		raw, err := sql.Open("sqlite3_with_fk", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx, err := raw.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = migrations[0].up(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tx.Exec("INSERT INTO accounts (number, name, type, currency, balance) VALUES ('123', 'Checking', 0, 'CAD', 100)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tx.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw.Close()
+
+		db, err := New(path)
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+		defer db.Close()
+
+		acc, err := db.FindAccount("123")
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if acc == nil || acc.Number != "123" {
+			t.Errorf("wants account 123, got %v", acc)
+		}
+
+		_, err = db.Exec("SELECT import_id FROM transactions")
+		if err != nil {
+			t.Errorf("wants import_id column, got %s", err)
+		}
+
+		list, err := db.Migrations()
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		for _, m := range list {
+			if m.AppliedAt.IsZero() {
+				t.Errorf("wants migration %d applied, got pending", m.Version)
+			}
+		}
+	})
+
+	t.Run("Idempotent", func(t *testing.T) {
+		db, err := New(path)
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+		defer db.Close()
+
+		var n int
+		err = db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&n)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if n != len(migrations) {
+			t.Errorf("wants %d applied migrations, got %d", len(migrations), n)
+		}
+	})
+}
+
+func TestRuleAccountNumbers(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "waukeen_db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := tmpfile.Name()
+	defer os.Remove(path)
+
+	raw, err := sql.Open("sqlite3_with_fk", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	tx, err := raw.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	for _, m := range migrations[:13] {
+		err = m.up(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, q := range []string{
+		"INSERT INTO accounts (id, number, type) VALUES (1, '123', 0), (2, '456', 0)",
+		"INSERT INTO rules (type, match, result, accounts) VALUES (2, 'dominos', 'pizza', '2,9')",
+		"INSERT INTO rules (type, match, result) VALUES (2, 'subway', 'lunch')",
+	} {
+		_, err = tx.Exec(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = ruleAccountNumbers(tx)
+	if err != nil {
+		t.Fatalf("wants no error, got %s", err)
+	}
+
+	rows, err := tx.Query("SELECT accounts FROM rules ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var accounts string
+		err = rows.Scan(&accounts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, accounts)
+	}
+
+	want := []string{"456,9", ""}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants %q accounts, got %q", want, got)
+	}
+}
@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"strings"
+)
+
+// query builds a SELECT or DELETE statement out of WHERE clauses, keeping
+// every user supplied value as a placeholder argument.
+type query struct {
+	base    string
+	clauses []string
+	args    []interface{}
+	suffix  string
+}
+
+func newQuery(base string, args ...interface{}) *query {
+	return &query{base: base, args: args}
+}
+
+func (q *query) where(clause string, args ...interface{}) *query {
+	q.clauses = append(q.clauses, clause)
+	q.args = append(q.args, args...)
+	return q
+}
+
+// in matches column against any of the values, an empty list matches nothing.
+func (q *query) in(column string, values ...interface{}) *query {
+	if len(values) == 0 {
+		return q.where("0")
+	}
+	return q.where(column+" IN ("+placeholders(len(values))+")", values...)
+}
+
+// notIn excludes values from column, an empty list excludes nothing.
+func (q *query) notIn(column string, values ...interface{}) *query {
+	if len(values) == 0 {
+		return q.where("1")
+	}
+	return q.where(column+" NOT IN ("+placeholders(len(values))+")", values...)
+}
+
+// startsWith matches column against a literal prefix, escaping LIKE wildcards.
+func (q *query) startsWith(column, prefix string) *query {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return q.where(column+` LIKE ? ESCAPE '\'`, r.Replace(prefix)+"%")
+}
+
+func (q *query) then(suffix string, args ...interface{}) *query {
+	q.suffix += " " + suffix
+	q.args = append(q.args, args...)
+	return q
+}
+
+func (q *query) String() string {
+	s := q.base
+	if len(q.clauses) > 0 {
+		s += " WHERE " + strings.Join(q.clauses, " AND ")
+	}
+	return s + q.suffix
+}
+
+func (q *query) Args() []interface{} {
+	return q.args
+}
+
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+func strArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
@@ -0,0 +1,191 @@
+package sqlite
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luizbranco/waukeen"
+)
+
+func TestQuery(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query *query
+		sql   string
+		args  []interface{}
+	}{
+		{
+			name:  "no clauses",
+			query: newQuery("SELECT id FROM tags"),
+			sql:   "SELECT id FROM tags",
+		},
+		{
+			name: "in list",
+			query: newQuery("SELECT id FROM tags").
+				in("name", "food", "gift").
+				where("id > ?", 1),
+			sql:  "SELECT id FROM tags WHERE name IN (?, ?) AND id > ?",
+			args: []interface{}{"food", "gift", 1},
+		},
+		{
+			name:  "empty in list",
+			query: newQuery("SELECT id FROM tags").in("name"),
+			sql:   "SELECT id FROM tags WHERE 0",
+		},
+		{
+			name:  "empty not in list",
+			query: newQuery("SELECT id FROM tags").notIn("name"),
+			sql:   "SELECT id FROM tags WHERE 1",
+		},
+		{
+			name: "prefix with wildcards",
+			query: newQuery("SELECT id FROM tags").
+				startsWith("name", `50%_off\`).
+				then("ORDER BY id"),
+			sql:  `SELECT id FROM tags WHERE name LIKE ? ESCAPE '\' ORDER BY id`,
+			args: []interface{}{`50\%\_off\\%`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.query.String(); got != tc.sql {
+				t.Errorf("wants %q, got %q", tc.sql, got)
+			}
+			if got := tc.query.Args(); !reflect.DeepEqual(got, tc.args) {
+				t.Errorf("wants %v, got %v", tc.args, got)
+			}
+		})
+	}
+}
+
+var hostile = []string{
+	"o'reilly",
+	"'; DROP TABLE transactions; --",
+	`") OR 1=1 --`,
+	"1) OR (1=1",
+	"100%",
+	"a_b",
+	`back\slash`,
+}
+
+func TestHostileInputs(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	acc := testAccount(db)
+
+	for i, name := range hostile {
+		tr := &waukeen.Transaction{
+			AccountID: acc.ID,
+			FITID:     name,
+			Type:      waukeen.Debit,
+			Title:     name,
+			Date:      time.Date(2016, 10, i+1, 0, 0, 0, 0, time.UTC),
+			Tags:      []string{name},
+		}
+		err := db.CreateTransaction(tr)
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+	}
+
+	t.Run("Find Transactions", func(t *testing.T) {
+		for _, name := range hostile {
+			opts := waukeen.TransactionsDBOptions{
+				Accounts: []string{acc.ID},
+				FITIDs:   []string{name},
+				Tags:     []string{name},
+			}
+			trs, err := db.FindTransactions(opts)
+			if err != nil {
+				t.Errorf("wants no error for %q, got %s", name, err)
+			}
+			if len(trs) != 1 || trs[0].Title != name {
+				t.Errorf("wants only transaction %q, got %+v", name, trs)
+			}
+		}
+	})
+
+	t.Run("Hostile Accounts", func(t *testing.T) {
+		for _, name := range hostile {
+			opts := waukeen.TransactionsDBOptions{Accounts: []string{name}}
+			trs, err := db.FindTransactions(opts)
+			if err != nil {
+				t.Errorf("wants no error for %q, got %s", name, err)
+			}
+			if len(trs) != 0 {
+				t.Errorf("wants no transactions for account %q, got %+v", name, trs)
+			}
+
+			accs, err := db.FindAccounts(name)
+			if err != nil {
+				t.Errorf("wants no error for %q, got %s", name, err)
+			}
+			if len(accs) != 0 {
+				t.Errorf("wants no accounts for %q, got %+v", name, accs)
+			}
+
+			rules, err := db.FindRules(name)
+			if err != nil {
+				t.Errorf("wants no error for %q, got %s", name, err)
+			}
+			if len(rules) != 0 {
+				t.Errorf("wants no rules for %q, got %+v", name, rules)
+			}
+		}
+	})
+
+	t.Run("Find Tags", func(t *testing.T) {
+		for _, name := range hostile {
+			tags, err := db.FindTags(name)
+			if err != nil {
+				t.Errorf("wants no error for %q, got %s", name, err)
+			}
+			if len(tags) != 1 || tags[0].Name != name {
+				t.Errorf("wants only tag %q, got %+v", name, tags)
+			}
+		}
+
+		tags, err := db.FindTags("%")
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(tags) != 0 {
+			t.Errorf("wants wildcard to be literal, got %+v", tags)
+		}
+	})
+
+	t.Run("Update Transaction", func(t *testing.T) {
+		tr, err := db.FindTransaction("1")
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		tr.Tags = []string{hostile[0], hostile[1], hostile[2]}
+		err = db.UpdateTransaction(tr)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		got, err := db.FindTransaction("1")
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		want := []string{hostile[2], hostile[1], hostile[0]}
+		if !reflect.DeepEqual(want, got.Tags) {
+			t.Errorf("wants %q, got %q", want, got.Tags)
+		}
+
+		trs, err := db.FindTransactions(waukeen.TransactionsDBOptions{})
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(trs) != len(hostile) {
+			t.Errorf("wants %d transactions, got %d", len(hostile), len(trs))
+		}
+	})
+}
@@ -15,6 +15,7 @@ import (
 
 type DB struct {
 	*sql.DB
+	tx *sql.Tx
 }
 
 func init() {
@@ -27,80 +28,73 @@ func init() {
 		})
 }
 
+// New opens the database at path and applies any pending migration.
 func New(path string) (*DB, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Migrate()
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Open opens the database at path without touching its schema.
+func Open(path string) (*DB, error) {
 	db, err := sql.Open("sqlite3_with_fk", path)
 	if err != nil {
 		return nil, err
 	}
 
-	queries := []string{
-		`
-		CREATE TABLE IF NOT EXISTS accounts(
-			id INTEGER PRIMARY KEY,
-			number TEXT NOT NULL CHECK(number <> ''),
-			name TEXT,
-			type INTEGER NOT NULL,
-			currency TEXT,
-			balance INTEGER
-		);
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS transactions(
-			id INTEGER PRIMARY KEY,
-			account_id INTEGER NOT NULL,
-			fitid TEXT NOT NULL CHECK(fitid <> ''),
-			type INTEGER NOT NULL,
-			title TEXT NOT NULL CHECK(title <> ''),
-			alias TEXT,
-			description TEXT,
-			amount INTEGER,
-			date DATETIME,
-			FOREIGN KEY(account_id) REFERENCES accounts(id) ON DELETE CASCADE
-		);
-		`,
-		`
-		CREATE UNIQUE INDEX IF NOT EXISTS account_fitid ON
-		transactions(account_id, fitid)
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS tags(
-			id INTEGER PRIMARY KEY,
-			name TEXT NOT NULL UNIQUE CHECK(name <> ''),
-			monthly_budget INTEGER
-		);
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS transaction_tags(
-			id INTEGER PRIMARY KEY,
-			transaction_id INTEGER NOT NULL,
-			tag_id INTEGER NOT NULL,
-			FOREIGN KEY(transaction_id) REFERENCES transactions(id) ON DELETE CASCADE
-			FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
-		);
-		`,
-		`
-		CREATE UNIQUE INDEX IF NOT EXISTS transaction_tag ON
-		transaction_tags(transaction_id, tag_id)
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS rules(
-			id INTEGER PRIMARY KEY,
-			type INTEGER NOT NULL,
-			match TEXT NOT NULL CHECK(match <> ''),
-			result TEXT NOT NULL
-		);
-		`,
-	}
-
-	for _, q := range queries {
-		_, err = db.Exec(q)
+	return &DB{DB: db}, nil
+}
 
-		if err != nil {
-			return nil, err
-		}
+// Exec, Query and QueryRow shadow the embedded *sql.DB methods so every
+// query runs inside the transaction when the DB was handed out by WithTx.
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if db.tx != nil {
+		return db.tx.Exec(query, args...)
+	}
+	return db.DB.Exec(query, args...)
+}
+
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if db.tx != nil {
+		return db.tx.Query(query, args...)
+	}
+	return db.DB.Query(query, args...)
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	if db.tx != nil {
+		return db.tx.QueryRow(query, args...)
+	}
+	return db.DB.QueryRow(query, args...)
+}
+
+// WithTx runs fn in a single database transaction, rolling it back if fn
+// returns an error. Calls nested inside fn join the outer transaction.
+func (db *DB) WithTx(fn func(waukeen.Database) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "begin transaction")
+	}
+
+	err = fn(&DB{DB: db.DB, tx: tx})
+	if err != nil {
+		tx.Rollback()
+		return err
 	}
 
-	return &DB{db}, nil
+	return errors.Wrap(tx.Commit(), "commit transaction")
 }
 
 func (db *DB) CreateAccount(a *waukeen.Account) error {
@@ -125,21 +119,27 @@ func (db *DB) CreateAccount(a *waukeen.Account) error {
 }
 
 func (db *DB) DeleteAccount(id string) error {
-	return errors.New("not implemented")
+	res, err := db.Exec("DELETE FROM accounts where id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, "delete account")
+	}
+	qt, _ := res.RowsAffected()
+	if qt == 0 {
+		return errors.New("invalid account id")
+	}
+	return nil
 }
 
 func (db *DB) FindAccounts(ids ...string) ([]waukeen.Account, error) {
 	var accounts []waukeen.Account
-	var query string
 
-	if len(ids) == 0 {
-		query = "SELECT id, number, name, type, currency, balance FROM accounts"
-	} else {
-		query = fmt.Sprintf(`SELECT id, number, name, type, currency, balance FROM
-		accounts where id IN (%s)`, toInCodition(ids))
+	q := newQuery("SELECT id, number, name, type, currency, balance FROM accounts")
+
+	if len(ids) > 0 {
+		q.in("id", strArgs(ids)...)
 	}
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(q.String(), q.Args()...)
 	if err != nil {
 		return nil, errors.Wrap(err, "query accounts")
 	}
@@ -184,11 +184,12 @@ func (db *DB) UpdateAccount(a *waukeen.Account) error {
 
 func (db *DB) CreateTransaction(t *waukeen.Transaction) error {
 	q := `INSERT into transactions
-	(account_id, fitid, type, title, alias, description, amount, date)
-	values (?, ?, ?, ?, ?, ?, ?, ?);`
+	(account_id, fitid, type, title, alias, description, amount, date, import_id,
+	alias_edited, tags_edited) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
 
 	res, err := db.Exec(q, t.AccountID, t.FITID, t.Type, t.Title, t.Alias,
-		t.Description, t.Amount, t.Date)
+		t.Description, t.Amount, t.Date, nullString(t.ImportID), t.AliasEdited,
+		t.TagsEdited)
 
 	if err != nil {
 		return errors.Wrap(err, "create transaction")
@@ -222,12 +223,14 @@ func (db *DB) CreateTransaction(t *waukeen.Transaction) error {
 }
 
 func (db *DB) UpdateTransaction(t *waukeen.Transaction) error {
-	q := fmt.Sprintf(`DELETE FROM transaction_tags WHERE id IN  (SELECT
-		transaction_tags.id FROM transaction_tags INNER JOIN tags ON tags.id =
-		transaction_tags.tag_id WHERE transaction_tags.transaction_id = ? AND
-		tags.name NOT IN (%s))`, toInCodition(t.Tags))
+	del := newQuery(`SELECT transaction_tags.id FROM transaction_tags INNER JOIN
+	tags ON tags.id = transaction_tags.tag_id`).
+		where("transaction_tags.transaction_id = ?", t.ID).
+		notIn("tags.name", strArgs(t.Tags)...)
+
+	q := "DELETE FROM transaction_tags WHERE id IN (" + del.String() + ")"
 
-	_, err := db.Exec(q, t.ID)
+	_, err := db.Exec(q, del.Args()...)
 
 	if err != nil {
 		return err
@@ -250,10 +253,10 @@ func (db *DB) UpdateTransaction(t *waukeen.Transaction) error {
 	}
 
 	q = `UPDATE transactions SET account_id=?, fitid=?, type=?, title=?, alias=?,
-	description=?, amount=?, date=? WHERE id=?`
+	description=?, amount=?, date=?, alias_edited=?, tags_edited=? WHERE id=?`
 
 	_, err = db.Exec(q, t.AccountID, t.FITID, t.Type, t.Title, t.Alias,
-		t.Description, t.Amount, t.Date, t.ID)
+		t.Description, t.Amount, t.Date, t.AliasEdited, t.TagsEdited, t.ID)
 
 	if err != nil {
 		return errors.Wrap(err, "update transaction")
@@ -263,17 +266,31 @@ func (db *DB) UpdateTransaction(t *waukeen.Transaction) error {
 }
 
 func (db *DB) DeleteTransaction(id string) error {
-	return errors.New("not implemented")
+	res, err := db.Exec("DELETE FROM transactions where id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, "delete transaction")
+	}
+	qt, _ := res.RowsAffected()
+	if qt == 0 {
+		return errors.New("invalid transaction id")
+	}
+	return nil
 }
 
 func (db *DB) FindTransaction(id string) (*waukeen.Transaction, error) {
-	q := `SELECT id, account_id, fitid, type, title, alias, description, amount,
-	date FROM transactions WHERE id = ?`
+	q := `SELECT transactions.id, transactions.account_id, transactions.fitid,
+	transactions.type, transactions.title, transactions.alias,
+	transactions.description, transactions.amount, transactions.date,
+	IFNULL(transactions.import_id, ''), transactions.alias_edited,
+	transactions.tags_edited, IFNULL(accounts.number, '') FROM transactions
+	LEFT JOIN accounts ON accounts.id = transactions.account_id
+	WHERE transactions.id = ?`
 
 	t := &waukeen.Transaction{}
 
 	err := db.QueryRow(q, id).Scan(&t.ID, &t.AccountID, &t.FITID, &t.Type,
-		&t.Title, &t.Alias, &t.Description, &t.Amount, &t.Date)
+		&t.Title, &t.Alias, &t.Description, &t.Amount, &t.Date, &t.ImportID,
+		&t.AliasEdited, &t.TagsEdited, &t.AccountNumber)
 
 	if err != nil {
 		return nil, err
@@ -290,61 +307,92 @@ func (db *DB) FindTransaction(id string) (*waukeen.Transaction, error) {
 
 func (db *DB) FindTransactions(opts waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
 	var transactions []waukeen.Transaction
-	var query string
-	var clauses []string
+
+	// tags are concatenated with the unit separator so a single query loads
+	// every transaction along with its tags
+	q := newQuery(`SELECT transactions.id, transactions.account_id,
+	transactions.fitid, transactions.type, transactions.title,
+	transactions.alias, transactions.description, transactions.amount,
+	transactions.date, IFNULL(transactions.import_id, ''),
+	transactions.alias_edited, transactions.tags_edited,
+	IFNULL(accounts.number, ''),
+	IFNULL(GROUP_CONCAT(tags.name, char(31)), '') FROM transactions
+	LEFT JOIN accounts ON accounts.id = transactions.account_id
+	LEFT JOIN transaction_tags ON transactions.id =
+	transaction_tags.transaction_id LEFT JOIN tags ON tags.id =
+	transaction_tags.tag_id`)
 
 	if len(opts.Tags) > 0 {
-		query = `SELECT transactions.id, transactions.account_id,
-		transactions.fitid, transactions.type, transactions.title,
-		transactions.alias, transactions.description, transactions.amount,
-		transactions.date FROM transactions JOIN transaction_tags ON
-		transactions.id = transaction_tags.transaction_id JOIN tags ON tags.id
-		= transaction_tags.tag_id  `
-		tags := toInCodition(opts.Tags)
-		clauses = append(clauses, fmt.Sprintf("tags.name IN (%s)", tags))
-	} else {
-		query = `SELECT id, account_id, fitid, type, title, alias, description, amount, date
-	FROM transactions `
+		// every group of tags is a tag asked for and the ones below it
+		var groups [][]string
+		var tree *waukeen.TagTree
+
+		if opts.DescendantTags {
+			tags, err := db.AllTags()
+			if err != nil {
+				return nil, errors.Wrap(err, "find descendant tags")
+			}
+			tree = waukeen.NewTagTree(tags)
+		}
+
+		for _, tag := range distinct(opts.Tags) {
+			group := []string{tag}
+			if tree != nil {
+				group = append(group, tree.Descendants(tag)...)
+			}
+			groups = append(groups, group)
+		}
+
+		if !opts.MatchAllTags {
+			var all []string
+			for _, g := range groups {
+				all = append(all, g...)
+			}
+			groups = [][]string{all}
+		}
+
+		for _, g := range groups {
+			tagged := newQuery(`SELECT transaction_tags.transaction_id FROM
+			transaction_tags JOIN tags ON tags.id = transaction_tags.tag_id`)
+			tagged.in("tags.name", strArgs(g)...)
+
+			q.where("transactions.id IN ("+tagged.String()+")", tagged.Args()...)
+		}
 	}
 
 	if len(opts.Accounts) > 0 {
-		clause := "transactions.account_id IN (" + strings.Join(opts.Accounts, " ,") + ")"
-		clauses = append(clauses, clause)
+		q.in("transactions.account_id", strArgs(opts.Accounts)...)
+	}
+
+	if len(opts.FITIDs) > 0 {
+		q.in("transactions.fitid", strArgs(opts.FITIDs)...)
 	}
 
 	if len(opts.Types) > 0 {
-		var types []string
+		var types []interface{}
 
 		for _, t := range opts.Types {
-			types = append(types, strconv.Itoa(int(t)))
+			types = append(types, int(t))
 		}
 
-		clause := "transactions.type IN (" + strings.Join(types, " ,") + ")"
-		clauses = append(clauses, clause)
+		q.in("transactions.type", types...)
 	}
 
 	if !opts.Start.IsZero() {
-		clauses = append(clauses, "transactions.date >= "+opts.Start.Format("'2006-01-02'"))
+		q.where("transactions.date >= ?", opts.Start.Format("2006-01-02"))
 	}
 
 	if !opts.End.IsZero() {
 		end := opts.End
 		end = end.Add(time.Hour * 24)
-		clauses = append(clauses, "transactions.date <= "+end.Format("'2006-01-02'"))
+		q.where("transactions.date <= ?", end.Format("2006-01-02"))
 	}
 
-	if len(clauses) > 0 {
-		query += "WHERE "
-		query += strings.Join(clauses, " AND ")
-	}
-
-	if len(opts.Tags) > 0 {
-		query += "GROUP BY transactions.id"
-	}
+	q.then("GROUP BY transactions.id ORDER BY transactions.date")
 
-	query += " ORDER BY transactions.date"
+	query := q.String()
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, q.Args()...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "transaction query %s", query)
 	}
@@ -352,17 +400,18 @@ func (db *DB) FindTransactions(opts waukeen.TransactionsDBOptions) ([]waukeen.Tr
 
 	for rows.Next() {
 		t := waukeen.Transaction{}
+		var tags string
 		err = rows.Scan(&t.ID, &t.AccountID, &t.FITID, &t.Type, &t.Title, &t.Alias,
-			&t.Description, &t.Amount, &t.Date)
+			&t.Description, &t.Amount, &t.Date, &t.ImportID, &t.AliasEdited,
+			&t.TagsEdited, &t.AccountNumber, &tags)
 		if err != nil {
 			return nil, errors.Wrap(err, "scan transaction")
 		}
 
-		tags, err := db.findTags(t.ID)
-		if err != nil {
-			return nil, err
+		if tags != "" {
+			t.Tags = strings.Split(tags, "\x1f")
+			sort.Strings(t.Tags)
 		}
-		t.Tags = tags
 
 		transactions = append(transactions, t)
 	}
@@ -373,6 +422,18 @@ func (db *DB) FindTransactions(opts waukeen.TransactionsDBOptions) ([]waukeen.Tr
 	return transactions, nil
 }
 
+func distinct(values []string) []string {
+	seen := make(map[string]bool)
+	var r []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
 func (db *DB) findTags(transaction string) ([]string, error) {
 	q := `SELECT DISTINCT tags.name FROM transaction_tags JOIN tags on
 	transaction_tags.tag_id = tags.id WHERE transaction_tags.transaction_id = ?`
@@ -400,9 +461,29 @@ func (db *DB) findTags(transaction string) ([]string, error) {
 }
 
 func (db *DB) CreateRule(r *waukeen.Rule) error {
-	q := "INSERT into rules (type, match, result) values (?, ?, ?)"
+	err := r.Validate()
+	if err != nil {
+		return err
+	}
+
+	if r.Priority == 0 {
+		err = db.QueryRow("SELECT IFNULL(MAX(priority), 0) + 1 FROM rules").
+			Scan(&r.Priority)
+		if err != nil {
+			return errors.Wrap(err, "find rule priority")
+		}
+	}
+
+	q := `INSERT into rules (type, mode, field, match, result, min_amount,
+	max_amount, accounts, transaction_type, weekdays, first_day, last_day,
+	priority, stop) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	res, err := db.Exec(q, r.Type, r.Match, r.Result)
+	c := ruleConditions(r.Conditions)
+
+	res, err := db.Exec(q, r.Type, r.Mode, r.Field, r.Match, r.Result,
+		c.MinAmount, c.MaxAmount, strings.Join(c.Accounts, ","),
+		c.TransactionType, joinWeekdays(c.Weekdays), c.FirstDay, c.LastDay,
+		r.Priority, r.Stop)
 
 	if err != nil {
 		return errors.Wrap(err, "create rule")
@@ -419,18 +500,49 @@ func (db *DB) CreateRule(r *waukeen.Rule) error {
 	return nil
 }
 
+func (db *DB) UpdateRule(r *waukeen.Rule) error {
+	err := r.Validate()
+	if err != nil {
+		return err
+	}
+
+	q := `UPDATE rules SET type=?, mode=?, field=?, match=?, result=?,
+	min_amount=?, max_amount=?, accounts=?, transaction_type=?, weekdays=?,
+	first_day=?, last_day=?, priority=?, stop=? where id = ?`
+
+	c := ruleConditions(r.Conditions)
+
+	res, err := db.Exec(q, r.Type, r.Mode, r.Field, r.Match, r.Result,
+		c.MinAmount, c.MaxAmount, strings.Join(c.Accounts, ","),
+		c.TransactionType, joinWeekdays(c.Weekdays), c.FirstDay, c.LastDay,
+		r.Priority, r.Stop, r.ID)
+
+	if err != nil {
+		return errors.Wrap(err, "update rule")
+	}
+
+	qt, _ := res.RowsAffected()
+	if qt == 0 {
+		return errors.New("invalid rule id")
+	}
+
+	return nil
+}
+
 func (db *DB) FindRules(ids ...string) ([]waukeen.Rule, error) {
 	var rules []waukeen.Rule
-	var query string
 
-	if len(ids) == 0 {
-		query = "SELECT id, type, match, result FROM rules"
-	} else {
-		query = fmt.Sprintf(`SELECT id, type, match, result FROM rules where id IN
-		(%s)`, toInCodition(ids))
+	q := newQuery(`SELECT id, type, mode, field, match, result, min_amount,
+	max_amount, accounts, transaction_type, weekdays, first_day, last_day,
+	priority, stop FROM rules`)
+
+	if len(ids) > 0 {
+		q.in("id", strArgs(ids)...)
 	}
 
-	rows, err := db.Query(query + " ORDER BY match COLLATE NOCASE")
+	q.then("ORDER BY priority, id")
+
+	rows, err := db.Query(q.String(), q.Args()...)
 	if err != nil {
 		return nil, err
 	}
@@ -438,16 +550,48 @@ func (db *DB) FindRules(ids ...string) ([]waukeen.Rule, error) {
 
 	for rows.Next() {
 		r := waukeen.Rule{}
-		err = rows.Scan(&r.ID, &r.Type, &r.Match, &r.Result)
+		c := &waukeen.RuleConditions{}
+		var accounts, weekdays string
+
+		err = rows.Scan(&r.ID, &r.Type, &r.Mode, &r.Field, &r.Match, &r.Result,
+			&c.MinAmount, &c.MaxAmount, &accounts, &c.TransactionType, &weekdays,
+			&c.FirstDay, &c.LastDay, &r.Priority, &r.Stop)
 		if err != nil {
 			return nil, err
 		}
+
+		if accounts != "" {
+			c.Accounts = strings.Split(accounts, ",")
+		}
+		c.Weekdays = splitWeekdays(weekdays)
+
+		if !c.Empty() {
+			r.Conditions = c
+		}
+
 		rules = append(rules, r)
 	}
 	err = rows.Err()
 	return rules, err
 }
 
+func (db *DB) ReorderRules(ids []string) error {
+	return db.WithTx(func(tx waukeen.Database) error {
+		db := tx.(*DB)
+		for i, id := range ids {
+			res, err := db.Exec("UPDATE rules SET priority = ? WHERE id = ?", i+1, id)
+			if err != nil {
+				return errors.Wrap(err, "reorder rules")
+			}
+			qt, _ := res.RowsAffected()
+			if qt == 0 {
+				return errors.New("invalid rule id")
+			}
+		}
+		return nil
+	})
+}
+
 func (db *DB) DeleteRule(id string) error {
 	res, err := db.Exec("DELETE FROM rules where id = ?", id)
 	if err != nil {
@@ -461,6 +605,13 @@ func (db *DB) DeleteRule(id string) error {
 }
 
 func (db *DB) CreateStatement(stmt waukeen.Statement,
+	transformer waukeen.TransactionTransformer) error {
+	return db.WithTx(func(tx waukeen.Database) error {
+		return tx.(*DB).createStatement(stmt, transformer)
+	})
+}
+
+func (db *DB) createStatement(stmt waukeen.Statement,
 	transformer waukeen.TransactionTransformer) error {
 	number := stmt.Account.Number
 
@@ -478,12 +629,32 @@ func (db *DB) CreateStatement(stmt waukeen.Statement,
 		return err
 	}
 
-	rules, err := db.FindRules()
+	imp := stmt.Import
+
+	if imp != nil {
+		imp.AccountID = acc.ID
+		if imp.Date.IsZero() {
+			imp.Date = time.Now()
+		}
+		err = db.createImport(imp)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var rules []waukeen.Rule
+
+	if transformer != nil {
+		rules, err = db.FindRules()
+	}
 
 	if err != nil {
 		return err
 	}
 
+	rules = waukeen.CompileRules(rules)
+
 	for _, tn := range stmt.Transactions {
 		q := `SELECT EXISTS(SELECT 1 FROM transactions WHERE account_id=? AND
 		fitid=? LIMIT 1)`
@@ -491,14 +662,24 @@ func (db *DB) CreateStatement(stmt waukeen.Statement,
 		var count int
 		err := db.QueryRow(q, acc.ID, tn.FITID).Scan(&count)
 
+		if err != nil {
+			return errors.Wrap(err, "find duplicated transaction")
+		}
+
 		if count > 0 {
+			if imp != nil {
+				imp.Skipped++
+			}
 			continue
 		}
 
 		t := &tn
 		t.AccountID = acc.ID
-		for _, r := range rules {
-			transformer.Transform(t, r)
+		t.AccountNumber = acc.Number
+		waukeen.ApplyRules(t, rules, transformer)
+		if imp != nil {
+			t.ImportID = imp.ID
+			imp.Created++
 		}
 		err = db.CreateTransaction(t)
 		if err != nil {
@@ -506,61 +687,383 @@ func (db *DB) CreateStatement(stmt waukeen.Statement,
 		}
 	}
 
+	if imp == nil {
+		return nil
+	}
+
+	_, err = db.Exec("UPDATE imports SET created=?, skipped=? WHERE id=?",
+		imp.Created, imp.Skipped, imp.ID)
+
+	return errors.Wrap(err, "update import")
+}
+
+func (db *DB) createImport(imp *waukeen.Import) error {
+	q := `INSERT into imports (filename, importer, account_id, date, created,
+	skipped) values (?, ?, ?, ?, ?, ?)`
+
+	res, err := db.Exec(q, imp.Filename, imp.Importer, imp.AccountID, imp.Date,
+		imp.Created, imp.Skipped)
+
+	if err != nil {
+		return errors.Wrap(err, "create import")
+	}
+
+	id, err := res.LastInsertId()
+
+	if err != nil {
+		return errors.Wrap(err, "retrieve last import id")
+	}
+
+	imp.ID = strconv.FormatInt(id, 10)
+
 	return nil
 }
 
-func (db *DB) CreateTag(t *waukeen.Tag) error {
-	q := `INSERT into tags (name, monthly_budget) values (?, ?)`
+func (db *DB) CreateTransactionEdit(e *waukeen.TransactionEdit) error {
+	if e.Date.IsZero() {
+		e.Date = time.Now()
+	}
 
-	res, err := db.Exec(q, t.Name, t.MonthlyBudget)
+	res, err := db.Exec(`INSERT INTO transaction_edits (transaction_id, title,
+	alias, tag, date) VALUES (?, ?, ?, ?, ?)`, e.TransactionID, e.Title,
+		e.Alias, e.Tag, e.Date)
 
 	if err != nil {
-		return fmt.Errorf("error creating tag: %s", err)
+		return errors.Wrap(err, "create transaction edit")
 	}
 
 	id, err := res.LastInsertId()
 
 	if err != nil {
-		return fmt.Errorf("error retrieving last tag id: %s", err)
+		return errors.Wrap(err, "retrieve last transaction edit id")
 	}
 
-	t.ID = strconv.FormatInt(id, 10)
+	e.ID = strconv.FormatInt(id, 10)
+
+	return nil
+}
+
+func (db *DB) FindTransactionEdits() ([]waukeen.TransactionEdit, error) {
+	var edits []waukeen.TransactionEdit
+
+	rows, err := db.Query(`SELECT id, transaction_id, title, alias, tag, date
+	FROM transaction_edits ORDER BY date, id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "query transaction edits")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := waukeen.TransactionEdit{}
+		err = rows.Scan(&e.ID, &e.TransactionID, &e.Title, &e.Alias, &e.Tag,
+			&e.Date)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan transaction edits")
+		}
+		edits = append(edits, e)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "find transaction edits")
+	}
+	return edits, nil
+}
+
+func (db *DB) FindTagModel() (*waukeen.TagModel, error) {
+	m := &waukeen.TagModel{
+		Tags:   make(map[string]int),
+		Tokens: make(map[string]map[string]int),
+	}
+
+	rows, err := db.Query("SELECT tag, transactions FROM tag_model_tags")
+	if err != nil {
+		return nil, errors.Wrap(err, "query tag model")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		var n int
+		err = rows.Scan(&tag, &n)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan tag model")
+		}
+		m.Tags[tag] = n
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "find tag model")
+	}
+
+	rows, err = db.Query("SELECT tag, token, count FROM tag_model_tokens")
+	if err != nil {
+		return nil, errors.Wrap(err, "query tag model tokens")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag, token string
+		var n int
+		err = rows.Scan(&tag, &token, &n)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan tag model tokens")
+		}
+		counts := m.Tokens[tag]
+		if counts == nil {
+			counts = make(map[string]int)
+			m.Tokens[tag] = counts
+		}
+		counts[token] = n
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "find tag model tokens")
+	}
+
+	return m, nil
+}
+
+func (db *DB) SaveTagModel(m *waukeen.TagModel) error {
+	return db.WithTx(func(tx waukeen.Database) error {
+		db := tx.(*DB)
+
+		for _, table := range []string{"tag_model_tags", "tag_model_tokens"} {
+			_, err := db.Exec("DELETE FROM " + table)
+			if err != nil {
+				return errors.Wrap(err, "clear tag model")
+			}
+		}
 
+		for tag, n := range m.Tags {
+			_, err := db.Exec(`INSERT INTO tag_model_tags (tag, transactions)
+			VALUES (?, ?)`, tag, n)
+			if err != nil {
+				return errors.Wrap(err, "save tag model")
+			}
+		}
+
+		for tag, counts := range m.Tokens {
+			for token, n := range counts {
+				_, err := db.Exec(`INSERT INTO tag_model_tokens (tag, token, count)
+				VALUES (?, ?, ?)`, tag, token, n)
+				if err != nil {
+					return errors.Wrap(err, "save tag model tokens")
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (db *DB) FindImports() ([]waukeen.Import, error) {
+	var imports []waukeen.Import
+
+	q := `SELECT id, filename, importer, account_id, date, created, skipped
+	FROM imports ORDER BY date DESC, id DESC`
+
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, errors.Wrap(err, "query imports")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		i := waukeen.Import{}
+		err = rows.Scan(&i.ID, &i.Filename, &i.Importer, &i.AccountID, &i.Date,
+			&i.Created, &i.Skipped)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan imports")
+		}
+		imports = append(imports, i)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "find imports")
+	}
+	return imports, nil
+}
+
+// DeleteImport removes the import and, through the foreign key cascades, its
+// transactions and their tag links.
+func (db *DB) DeleteImport(id string) error {
+	res, err := db.Exec("DELETE FROM imports where id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, "delete import")
+	}
+	qt, _ := res.RowsAffected()
+	if qt == 0 {
+		return errors.New("invalid import id")
+	}
 	return nil
 }
 
+// tagQuery selects tags along with the name of their parent.
+const tagQuery = `SELECT tags.id, tags.name, tags.monthly_budget, tags.rollover,
+tags.rollover_cap, tags.income, IFNULL(parents.name, '') FROM tags
+LEFT JOIN tags AS parents ON parents.id = tags.parent_id`
+
+func (db *DB) CreateTag(t *waukeen.Tag) error {
+	return db.WithTx(func(tx waukeen.Database) error {
+		db := tx.(*DB)
+
+		parent, err := db.parentID(t)
+		if err != nil {
+			return err
+		}
+
+		q := `INSERT into tags (name, monthly_budget, rollover, rollover_cap,
+		income, parent_id) values (?, ?, ?, ?, ?, ?)`
+
+		res, err := db.Exec(q, t.Name, t.MonthlyBudget, t.Rollover, t.RolloverCap,
+			t.Income, parent)
+
+		if err != nil {
+			return fmt.Errorf("error creating tag: %s", err)
+		}
+
+		id, err := res.LastInsertId()
+
+		if err != nil {
+			return fmt.Errorf("error retrieving last tag id: %s", err)
+		}
+
+		t.ID = strconv.FormatInt(id, 10)
+
+		return db.saveTagBudgets(t)
+	})
+}
+
+// UpdateTag also replaces the month budgets of the tag by t.Budgets.
 func (db *DB) UpdateTag(t *waukeen.Tag) error {
-	_, err := db.Exec("UPDATE tags SET name=?, monthly_budget=? where id = ?",
-		t.Name, t.MonthlyBudget, t.ID)
-	return err
+	return db.WithTx(func(tx waukeen.Database) error {
+		db := tx.(*DB)
+
+		parent, err := db.parentID(t)
+		if err != nil {
+			return err
+		}
+
+		// a tag can't be moved below itself
+		for id := parent; id.Valid; {
+			if id.String == t.ID {
+				return errors.Errorf("tag %s can't be below itself", t.Name)
+			}
+			err = db.QueryRow("SELECT parent_id FROM tags WHERE id = ?", id.String).Scan(&id)
+			if err != nil {
+				return errors.Wrap(err, "find tag parent")
+			}
+		}
+
+		_, err = db.Exec(`UPDATE tags SET name=?, monthly_budget=?, rollover=?,
+		rollover_cap=?, income=?, parent_id=? where id = ?`, t.Name,
+			t.MonthlyBudget, t.Rollover, t.RolloverCap, t.Income, parent, t.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = db.Exec("DELETE FROM budgets WHERE tag_id = ?", t.ID)
+		if err != nil {
+			return errors.Wrap(err, "clear tag budgets")
+		}
+
+		return db.saveTagBudgets(t)
+	})
+}
+
+// parentID finds the id of the parent of the tag, creating it when missing.
+func (db *DB) parentID(t *waukeen.Tag) (sql.NullString, error) {
+	if t.Parent == "" {
+		return sql.NullString{}, nil
+	}
+	if t.Parent == t.Name {
+		return sql.NullString{}, errors.Errorf("tag %s can't be its own parent", t.Name)
+	}
+
+	id, err := db.findOrCreateTag(t.Parent)
+	if err != nil {
+		return sql.NullString{}, errors.Wrap(err, "tag parent")
+	}
+	return nullString(id), nil
+}
+
+func (db *DB) saveTagBudgets(t *waukeen.Tag) error {
+	for _, b := range t.Budgets {
+		_, err := db.Exec(`INSERT INTO budgets (tag_id, month, amount)
+		VALUES (?, ?, ?)`, t.ID, firstOfMonth(b.Month), b.Amount)
+		if err != nil {
+			return errors.Wrap(err, "save tag budget")
+		}
+	}
+	return nil
+}
+
+// tagBudgets loads the month budgets of the tags by tag id, in month order.
+func (db *DB) tagBudgets(ids ...string) (map[string][]waukeen.TagBudget, error) {
+	q := newQuery("SELECT tag_id, month, amount FROM budgets").
+		in("tag_id", strArgs(ids)...).
+		then("ORDER BY month")
+
+	rows, err := db.Query(q.String(), q.Args()...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query tag budgets")
+	}
+	defer rows.Close()
+
+	budgets := make(map[string][]waukeen.TagBudget)
+	for rows.Next() {
+		var id string
+		var b waukeen.TagBudget
+		err = rows.Scan(&id, &b.Month, &b.Amount)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan tag budget")
+		}
+		budgets[id] = append(budgets[id], b)
+	}
+	return budgets, errors.Wrap(rows.Err(), "find tag budgets")
 }
 
 func (db *DB) DeleteTag(id string) error {
-	return errors.New("not implemented")
+	res, err := db.Exec("DELETE FROM tags where id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, "delete tag")
+	}
+	qt, _ := res.RowsAffected()
+	if qt == 0 {
+		return errors.New("invalid tag id")
+	}
+	return nil
 }
 
 func (db *DB) FindTag(name string) (*waukeen.Tag, error) {
-	q := "SELECT id, name, monthly_budget FROM tags where name = ?"
+	q := tagQuery + " WHERE tags.name = ?"
 
 	t := &waukeen.Tag{}
 
-	err := db.QueryRow(q, name).Scan(&t.ID, &t.Name, &t.MonthlyBudget)
+	err := db.QueryRow(q, name).Scan(&t.ID, &t.Name, &t.MonthlyBudget,
+		&t.Rollover, &t.RolloverCap, &t.Income, &t.Parent)
 
 	if err != nil {
 		return nil, fmt.Errorf("error finding tag: %s", err)
 	}
 
+	budgets, err := db.tagBudgets(t.ID)
+	if err != nil {
+		return nil, err
+	}
+	t.Budgets = budgets[t.ID]
+
 	return t, nil
 }
 
 func (db *DB) AllTags() ([]waukeen.Tag, error) {
-	return db.queryTags("SELECT id, name, monthly_budget FROM tags")
+	return db.queryTags(newQuery(tagQuery))
 }
 
-func (db *DB) queryTags(q string) ([]waukeen.Tag, error) {
+func (db *DB) queryTags(q *query) ([]waukeen.Tag, error) {
 	var tags []waukeen.Tag
 
-	rows, err := db.Query(q)
+	rows, err := db.Query(q.String(), q.Args()...)
 	if err != nil {
 		return nil, err
 	}
@@ -568,23 +1071,207 @@ func (db *DB) queryTags(q string) ([]waukeen.Tag, error) {
 
 	for rows.Next() {
 		t := waukeen.Tag{}
-		err = rows.Scan(&t.ID, &t.Name, &t.MonthlyBudget)
+		err = rows.Scan(&t.ID, &t.Name, &t.MonthlyBudget, &t.Rollover,
+			&t.RolloverCap, &t.Income, &t.Parent)
 		if err != nil {
 			return nil, err
 		}
 		tags = append(tags, t)
 	}
 	err = rows.Err()
-	return tags, err
-	return nil, nil
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(tags))
+	for i, t := range tags {
+		ids[i] = t.ID
+	}
+
+	budgets, err := db.tagBudgets(ids...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tags {
+		tags[i].Budgets = budgets[tags[i].ID]
+	}
+
+	return tags, nil
 }
 
 func (db *DB) FindTags(starts string) ([]waukeen.Tag, error) {
-	q := fmt.Sprintf("SELECT id, name, monthly_budget FROM tags where name LIKE '%s'",
-		starts+"%")
+	q := newQuery(tagQuery).startsWith("tags.name", starts)
 	return db.queryTags(q)
 }
 
-func toInCodition(args []string) string {
-	return "'" + strings.Join(args, "', '") + "'"
+// findOrCreateTag returns the id of the tag, creating it when missing.
+func (db *DB) findOrCreateTag(name string) (string, error) {
+	tag, err := db.FindTag(name)
+	if err != nil {
+		tag = &waukeen.Tag{Name: name}
+		err = db.CreateTag(tag)
+	}
+	if err != nil {
+		return "", err
+	}
+	return tag.ID, nil
+}
+
+func (db *DB) SetEnvelopeAssignment(a *waukeen.EnvelopeAssignment) error {
+	return db.WithTx(func(tx waukeen.Database) error {
+		db := tx.(*DB)
+
+		tag, err := db.findOrCreateTag(a.Tag)
+		if err != nil {
+			return errors.Wrap(err, "envelope assignment tag")
+		}
+
+		res, err := db.Exec(`INSERT OR REPLACE INTO envelope_assignments (tag_id,
+		month, amount) VALUES (?, ?, ?)`, tag, firstOfMonth(a.Month), a.Amount)
+		if err != nil {
+			return errors.Wrap(err, "set envelope assignment")
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return errors.Wrap(err, "retrieve last envelope assignment id")
+		}
+
+		a.ID = strconv.FormatInt(id, 10)
+
+		return nil
+	})
+}
+
+func (db *DB) CreateEnvelopeMove(m *waukeen.EnvelopeMove) error {
+	if m.From == m.To {
+		return errors.New("envelope move to the same envelope")
+	}
+
+	return db.WithTx(func(tx waukeen.Database) error {
+		db := tx.(*DB)
+
+		from, err := db.findOrCreateTag(m.From)
+		if err != nil {
+			return errors.Wrap(err, "envelope move from tag")
+		}
+
+		to, err := db.findOrCreateTag(m.To)
+		if err != nil {
+			return errors.Wrap(err, "envelope move to tag")
+		}
+
+		res, err := db.Exec(`INSERT INTO envelope_moves (from_tag_id, to_tag_id,
+		month, amount) VALUES (?, ?, ?, ?)`, from, to, firstOfMonth(m.Month),
+			m.Amount)
+		if err != nil {
+			return errors.Wrap(err, "create envelope move")
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return errors.Wrap(err, "retrieve last envelope move id")
+		}
+
+		m.ID = strconv.FormatInt(id, 10)
+
+		return nil
+	})
+}
+
+func (db *DB) DeleteEnvelopeMove(id string) error {
+	res, err := db.Exec("DELETE FROM envelope_moves WHERE id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, "delete envelope move")
+	}
+	qt, _ := res.RowsAffected()
+	if qt == 0 {
+		return errors.New("invalid envelope move id")
+	}
+	return nil
+}
+
+func (db *DB) FindEnvelopeLedger() (*waukeen.EnvelopeLedger, error) {
+	l := &waukeen.EnvelopeLedger{}
+
+	rows, err := db.Query(`SELECT envelope_assignments.id, tags.name,
+	envelope_assignments.month, envelope_assignments.amount FROM
+	envelope_assignments JOIN tags ON tags.id = envelope_assignments.tag_id
+	ORDER BY envelope_assignments.month, tags.name`)
+	if err != nil {
+		return nil, errors.Wrap(err, "query envelope assignments")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := waukeen.EnvelopeAssignment{}
+		err = rows.Scan(&a.ID, &a.Tag, &a.Month, &a.Amount)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan envelope assignments")
+		}
+		l.Assignments = append(l.Assignments, a)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "find envelope assignments")
+	}
+
+	rows, err = db.Query(`SELECT envelope_moves.id, from_tags.name, to_tags.name,
+	envelope_moves.month, envelope_moves.amount FROM envelope_moves
+	JOIN tags AS from_tags ON from_tags.id = envelope_moves.from_tag_id
+	JOIN tags AS to_tags ON to_tags.id = envelope_moves.to_tag_id
+	ORDER BY envelope_moves.month, envelope_moves.id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "query envelope moves")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := waukeen.EnvelopeMove{}
+		err = rows.Scan(&m.ID, &m.From, &m.To, &m.Month, &m.Amount)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan envelope moves")
+		}
+		l.Moves = append(l.Moves, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "find envelope moves")
+	}
+
+	return l, nil
+}
+
+func ruleConditions(c *waukeen.RuleConditions) waukeen.RuleConditions {
+	if c == nil {
+		return waukeen.RuleConditions{}
+	}
+	return *c
+}
+
+func joinWeekdays(days []time.Weekday) string {
+	list := make([]string, len(days))
+	for i, d := range days {
+		list[i] = strconv.Itoa(int(d))
+	}
+	return strings.Join(list, ",")
+}
+
+func splitWeekdays(s string) []time.Weekday {
+	var days []time.Weekday
+	for _, d := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(d)
+		if err == nil {
+			days = append(days, time.Weekday(n))
+		}
+	}
+	return days
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
 }
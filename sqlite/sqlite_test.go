@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -198,16 +199,17 @@ func TestCreateTransaction(t *testing.T) {
 
 	t.Run("Valid Transaction", func(t *testing.T) {
 		tr := &waukeen.Transaction{
-			ID:          "1",
-			AccountID:   acc.ID,
-			FITID:       "12345",
-			Type:        waukeen.Debit,
-			Title:       "First Transaction",
-			Alias:       "Renamed Transaction",
-			Description: "Surcharge",
-			Amount:      9999,
-			Date:        time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
-			Tags:        []string{"groceries", "restaurants"},
+			ID:            "1",
+			AccountID:     acc.ID,
+			AccountNumber: acc.Number,
+			FITID:         "12345",
+			Type:          waukeen.Debit,
+			Title:         "First Transaction",
+			Alias:         "Renamed Transaction",
+			Description:   "Surcharge",
+			Amount:        9999,
+			Date:          time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
+			Tags:          []string{"groceries", "restaurants"},
 		}
 		err := db.CreateTransaction(tr)
 
@@ -242,15 +244,16 @@ func TestFindTransaction(t *testing.T) {
 
 	t.Run("Valid Transaction", func(t *testing.T) {
 		tr := &waukeen.Transaction{
-			AccountID:   acc.ID,
-			FITID:       "12345",
-			Type:        waukeen.Debit,
-			Title:       "First Transaction",
-			Alias:       "Renamed Transaction",
-			Description: "Surcharge",
-			Amount:      9999,
-			Date:        time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
-			Tags:        []string{"groceries", "restaurants"},
+			AccountID:     acc.ID,
+			AccountNumber: acc.Number,
+			FITID:         "12345",
+			Type:          waukeen.Debit,
+			Title:         "First Transaction",
+			Alias:         "Renamed Transaction",
+			Description:   "Surcharge",
+			Amount:        9999,
+			Date:          time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
+			Tags:          []string{"groceries", "restaurants"},
 		}
 		err := db.CreateTransaction(tr)
 
@@ -284,16 +287,17 @@ func TestUpdateTransaction(t *testing.T) {
 	acc := testAccount(db)
 
 	tr := &waukeen.Transaction{
-		ID:          "1",
-		AccountID:   acc.ID,
-		FITID:       "12345",
-		Type:        waukeen.Debit,
-		Title:       "First Transaction",
-		Alias:       "Renamed Transaction",
-		Description: "Surcharge",
-		Amount:      9999,
-		Date:        time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
-		Tags:        []string{"groceries", "restaurants"},
+		ID:            "1",
+		AccountID:     acc.ID,
+		AccountNumber: acc.Number,
+		FITID:         "12345",
+		Type:          waukeen.Debit,
+		Title:         "First Transaction",
+		Alias:         "Renamed Transaction",
+		Description:   "Surcharge",
+		Amount:        9999,
+		Date:          time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
+		Tags:          []string{"groceries", "restaurants"},
 	}
 	err := db.CreateTransaction(tr)
 
@@ -304,6 +308,7 @@ func TestUpdateTransaction(t *testing.T) {
 	t.Run("Valid Transaction", func(t *testing.T) {
 		tr.FITID = "23456"
 		tr.Tags = []string{"food"}
+		tr.TagsEdited = true
 
 		err := db.UpdateTransaction(tr)
 		if err != nil {
@@ -360,6 +365,132 @@ func TestCreateRule(t *testing.T) {
 			t.Errorf("wants error, got none")
 		}
 	})
+
+	t.Run("Regex Rule", func(t *testing.T) {
+		r := &waukeen.Rule{
+			Type:   waukeen.TagRule,
+			Mode:   waukeen.RegexMatch,
+			Field:  waukeen.DescriptionField,
+			Match:  "^amzn",
+			Result: "shopping",
+		}
+		err := db.CreateRule(r)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		rules, err := db.FindRules(r.ID)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(rules) != 1 || !reflect.DeepEqual(*r, rules[0]) {
+			t.Errorf("wants %+v, got %+v", r, rules)
+		}
+	})
+
+	t.Run("Conditional Rule", func(t *testing.T) {
+		r := &waukeen.Rule{
+			Type:   waukeen.TagRule,
+			Match:  "amazon",
+			Result: "electronics",
+			Conditions: &waukeen.RuleConditions{
+				MinAmount:       50000,
+				Accounts:        []string{"1", "3"},
+				TransactionType: waukeen.Debit,
+				Weekdays:        []time.Weekday{time.Saturday, time.Sunday},
+				FirstDay:        28,
+				LastDay:         3,
+			},
+		}
+		err := db.CreateRule(r)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		rules, err := db.FindRules(r.ID)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(rules) != 1 || !reflect.DeepEqual(*r, rules[0]) {
+			t.Errorf("wants %+v, got %+v", r, rules)
+		}
+	})
+
+	t.Run("Invalid Regex Rule", func(t *testing.T) {
+		r := &waukeen.Rule{
+			Type:   waukeen.TagRule,
+			Mode:   waukeen.RegexMatch,
+			Match:  "amzn (",
+			Result: "shopping",
+		}
+		err := db.CreateRule(r)
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+}
+
+func TestUpdateRule(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	r := &waukeen.Rule{
+		Type:   waukeen.TagRule,
+		Match:  "dominos",
+		Result: "pizza",
+	}
+	err := db.CreateRule(r)
+
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+
+	t.Run("Valid Rule", func(t *testing.T) {
+		want := waukeen.Rule{
+			ID:       r.ID,
+			Type:     waukeen.ReplaceRule,
+			Mode:     waukeen.PrefixMatch,
+			Field:    waukeen.DescriptionField,
+			Match:    "domino",
+			Result:   "Dominos",
+			Priority: r.Priority,
+			Stop:     true,
+			Conditions: &waukeen.RuleConditions{
+				Accounts: []string{"1"},
+				Weekdays: []time.Weekday{time.Friday},
+			},
+		}
+
+		err := db.UpdateRule(&want)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		rules, err := db.FindRules(r.ID)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		got := []waukeen.Rule{want}
+		if !reflect.DeepEqual(got, rules) {
+			t.Errorf("wants %+v, got %+v", got, rules)
+		}
+	})
+
+	t.Run("Invalid Rule", func(t *testing.T) {
+		err := db.UpdateRule(&waukeen.Rule{ID: r.ID, Match: "dominos"})
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		err := db.UpdateRule(&waukeen.Rule{ID: "99", Type: waukeen.TagRule,
+			Match: "dominos", Result: "pizza"})
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
 }
 
 func TestDeleteRule(t *testing.T) {
@@ -391,6 +522,97 @@ func TestDeleteRule(t *testing.T) {
 		}
 	})
 }
+func TestDeleteAccount(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	acc := testAccount(db)
+	tr := &waukeen.Transaction{AccountID: acc.ID, FITID: "1", Title: "1st"}
+	err := db.CreateTransaction(tr)
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+
+	t.Run("Valid Account", func(t *testing.T) {
+		err := db.DeleteAccount(acc.ID)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		_, err = db.FindTransaction(tr.ID)
+		if err == nil {
+			t.Errorf("wants transaction deleted, got none")
+		}
+	})
+
+	t.Run("Invalid Account", func(t *testing.T) {
+		err := db.DeleteAccount("99")
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+}
+
+func TestDeleteTransaction(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	acc := testAccount(db)
+	tr := &waukeen.Transaction{AccountID: acc.ID, FITID: "1", Title: "1st",
+		Tags: []string{"food"}}
+	err := db.CreateTransaction(tr)
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+
+	t.Run("Valid Transaction", func(t *testing.T) {
+		err := db.DeleteTransaction(tr.ID)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		got, err := db.FindTransactions(waukeen.TransactionsDBOptions{})
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("wants no transactions, got %+v", got)
+		}
+	})
+
+	t.Run("Invalid Transaction", func(t *testing.T) {
+		err := db.DeleteTransaction("99")
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+}
+
+func TestDeleteTag(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	tag := &waukeen.Tag{Name: "food"}
+	err := db.CreateTag(tag)
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+
+	t.Run("Valid Tag", func(t *testing.T) {
+		err := db.DeleteTag(tag.ID)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+	})
+
+	t.Run("Invalid Tag", func(t *testing.T) {
+		err := db.DeleteTag("99")
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+}
+
 func TestFindTransactions(t *testing.T) {
 	db, path := testDB()
 	defer os.Remove(path)
@@ -399,39 +621,43 @@ func TestFindTransactions(t *testing.T) {
 	acc2 := testAccount(db)
 
 	tr1 := waukeen.Transaction{
-		ID:        "1",
-		AccountID: acc1.ID,
-		FITID:     "01",
-		Type:      waukeen.Debit,
-		Title:     "1st",
-		Date:      time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
-		Tags:      []string{"groceries", "restaurants"},
+		ID:            "1",
+		AccountID:     acc1.ID,
+		AccountNumber: acc1.Number,
+		FITID:         "01",
+		Type:          waukeen.Debit,
+		Title:         "1st",
+		Date:          time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
+		Tags:          []string{"groceries", "restaurants"},
 	}
 	tr2 := waukeen.Transaction{
-		ID:        "2",
-		AccountID: acc1.ID,
-		FITID:     "02",
-		Type:      waukeen.Credit,
-		Title:     "2nd",
-		Date:      time.Date(2016, 10, 5, 0, 0, 0, 0, time.UTC),
+		ID:            "2",
+		AccountID:     acc1.ID,
+		AccountNumber: acc1.Number,
+		FITID:         "02",
+		Type:          waukeen.Credit,
+		Title:         "2nd",
+		Date:          time.Date(2016, 10, 5, 0, 0, 0, 0, time.UTC),
 	}
 	tr3 := waukeen.Transaction{
-		ID:        "3",
-		AccountID: acc2.ID,
-		FITID:     "03",
-		Type:      waukeen.Debit,
-		Title:     "3rd",
-		Tags:      []string{"transportation"},
-		Date:      time.Date(2016, 10, 10, 0, 0, 0, 0, time.UTC),
+		ID:            "3",
+		AccountID:     acc2.ID,
+		AccountNumber: acc2.Number,
+		FITID:         "03",
+		Type:          waukeen.Debit,
+		Title:         "3rd",
+		Tags:          []string{"transportation"},
+		Date:          time.Date(2016, 10, 10, 0, 0, 0, 0, time.UTC),
 	}
 	tr4 := waukeen.Transaction{
-		ID:        "4",
-		AccountID: acc2.ID,
-		FITID:     "04",
-		Type:      waukeen.Credit,
-		Title:     "4th",
-		Date:      time.Date(2016, 10, 15, 0, 0, 0, 0, time.UTC),
-		Tags:      []string{"groceries"},
+		ID:            "4",
+		AccountID:     acc2.ID,
+		AccountNumber: acc2.Number,
+		FITID:         "04",
+		Type:          waukeen.Credit,
+		Title:         "4th",
+		Date:          time.Date(2016, 10, 15, 0, 0, 0, 0, time.UTC),
+		Tags:          []string{"groceries"},
 	}
 
 	for _, tr := range []waukeen.Transaction{tr1, tr2, tr3, tr4} {
@@ -441,6 +667,18 @@ func TestFindTransactions(t *testing.T) {
 		}
 	}
 
+	for _, name := range []string{"groceries", "restaurants"} {
+		tag, err := db.FindTag(name)
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+		tag.Parent = "food"
+		err = db.UpdateTag(tag)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+	}
+
 	cases := []struct {
 		opts waukeen.TransactionsDBOptions
 		want []waukeen.Transaction
@@ -495,6 +733,13 @@ func TestFindTransactions(t *testing.T) {
 			},
 			[]waukeen.Transaction{tr1, tr4},
 		},
+		{
+			waukeen.TransactionsDBOptions{
+				Accounts: []string{"2"},
+				FITIDs:   []string{"01", "04"},
+			},
+			[]waukeen.Transaction{tr4},
+		},
 		{
 			waukeen.TransactionsDBOptions{
 				Tags: []string{"groceries", "transportation"},
@@ -520,6 +765,48 @@ func TestFindTransactions(t *testing.T) {
 			},
 			[]waukeen.Transaction{tr1, tr3},
 		},
+		{
+			waukeen.TransactionsDBOptions{
+				Tags:         []string{"groceries", "restaurants"},
+				MatchAllTags: true,
+			},
+			[]waukeen.Transaction{tr1},
+		},
+		{
+			waukeen.TransactionsDBOptions{
+				Tags:         []string{"groceries", "groceries"},
+				MatchAllTags: true,
+			},
+			[]waukeen.Transaction{tr1, tr4},
+		},
+		{
+			waukeen.TransactionsDBOptions{
+				Tags:         []string{"groceries", "transportation"},
+				MatchAllTags: true,
+			},
+			nil,
+		},
+		{
+			waukeen.TransactionsDBOptions{
+				Tags: []string{"food"},
+			},
+			nil,
+		},
+		{
+			waukeen.TransactionsDBOptions{
+				Tags:           []string{"food"},
+				DescendantTags: true,
+			},
+			[]waukeen.Transaction{tr1, tr4},
+		},
+		{
+			waukeen.TransactionsDBOptions{
+				Tags:           []string{"food", "restaurants"},
+				MatchAllTags:   true,
+				DescendantTags: true,
+			},
+			[]waukeen.Transaction{tr1},
+		},
 	}
 
 	for _, c := range cases {
@@ -538,17 +825,19 @@ func TestFindRules(t *testing.T) {
 	defer os.Remove(path)
 
 	r1 := waukeen.Rule{
-		ID:     "1",
-		Type:   waukeen.TagRule,
-		Match:  "dominos",
-		Result: "pizza",
+		ID:       "1",
+		Type:     waukeen.TagRule,
+		Match:    "dominos",
+		Result:   "pizza",
+		Priority: 1,
 	}
 
 	r2 := waukeen.Rule{
-		ID:     "2",
-		Type:   waukeen.TagRule,
-		Match:  "dominos",
-		Result: "pizza",
+		ID:       "2",
+		Type:     waukeen.TagRule,
+		Match:    "dominos",
+		Result:   "pizza",
+		Priority: 2,
 	}
 
 	for _, r := range []waukeen.Rule{r1, r2} {
@@ -579,13 +868,86 @@ func TestFindRules(t *testing.T) {
 	}
 }
 
+func TestReorderRules(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	for _, m := range []string{"pizza", "burger", "tacos"} {
+		r := &waukeen.Rule{Type: waukeen.TagRule, Match: m, Result: "restaurants"}
+		err := db.CreateRule(r)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+	}
+
+	order := func() []string {
+		rules, err := db.FindRules()
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		var list []string
+		for _, r := range rules {
+			list = append(list, r.Match)
+		}
+		return list
+	}
+
+	t.Run("Creation Order", func(t *testing.T) {
+		want := []string{"pizza", "burger", "tacos"}
+		if got := order(); !reflect.DeepEqual(want, got) {
+			t.Errorf("wants %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Reordered", func(t *testing.T) {
+		err := db.ReorderRules([]string{"3", "1", "2"})
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		want := []string{"tacos", "pizza", "burger"}
+		if got := order(); !reflect.DeepEqual(want, got) {
+			t.Errorf("wants %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Appended After Reorder", func(t *testing.T) {
+		r := &waukeen.Rule{Type: waukeen.TagRule, Match: "sushi", Result: "restaurants"}
+		err := db.CreateRule(r)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		want := []string{"tacos", "pizza", "burger", "sushi"}
+		if got := order(); !reflect.DeepEqual(want, got) {
+			t.Errorf("wants %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Invalid Rule", func(t *testing.T) {
+		err := db.ReorderRules([]string{"2", "99"})
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+
+		want := []string{"tacos", "pizza", "burger", "sushi"}
+		if got := order(); !reflect.DeepEqual(want, got) {
+			t.Errorf("wants order rolled back %v, got %v", want, got)
+		}
+	})
+}
+
 func TestCreateStatement(t *testing.T) {
 	db, path := testDB()
 	defer os.Remove(path)
 
 	transformer := &mock.TransactionTransformer{}
-	transformer.TransformMethod = func(t *waukeen.Transaction, r waukeen.Rule) {
+	transformer.TransformMethod = func(t *waukeen.Transaction, r waukeen.Rule) bool {
+		if !r.Conditions.Match(t) {
+			return false
+		}
 		t.Alias = r.Result
+		return true
 	}
 
 	r := &waukeen.Rule{
@@ -611,13 +973,29 @@ func TestCreateStatement(t *testing.T) {
 
 	t.Run("Invalid Transaction", func(t *testing.T) {
 		stmt := waukeen.Statement{
-			Account:      waukeen.Account{Number: "12345"},
-			Transactions: []waukeen.Transaction{{Title: "FUCL"}},
+			Account: waukeen.Account{Number: "12345"},
+			Transactions: []waukeen.Transaction{
+				{FITID: "12345", Title: "Valid"},
+				{Title: "FUCL"},
+			},
 		}
 		err := db.CreateStatement(stmt, transformer)
 		if err == nil {
 			t.Errorf("wants error, got none")
 		}
+
+		_, err = db.FindAccount("12345")
+		if err == nil {
+			t.Errorf("wants account creation to be rolled back, got none")
+		}
+
+		trs, err := db.FindTransactions(waukeen.TransactionsDBOptions{})
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(trs) != 0 {
+			t.Errorf("wants transactions to be rolled back, got %+v", trs)
+		}
 	})
 
 	t.Run("Valid Statement", func(t *testing.T) {
@@ -653,9 +1031,357 @@ func TestCreateStatement(t *testing.T) {
 			t.Errorf("wants transaction alias to be %s, got %s", want, got)
 		}
 	})
-}
 
-func TestCreateTag(t *testing.T) {
+	t.Run("Account Rule", func(t *testing.T) {
+		r := &waukeen.Rule{
+			Type:       waukeen.ReplaceRule,
+			Match:      "something",
+			Result:     "Visa Alias",
+			Conditions: &waukeen.RuleConditions{Accounts: []string{"99999"}},
+		}
+		err := db.CreateRule(r)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		stmt := waukeen.Statement{
+			Account: waukeen.Account{Number: "99999"},
+			Transactions: []waukeen.Transaction{
+				{FITID: "1", Title: "First"},
+			},
+		}
+		err = db.CreateStatement(stmt, transformer)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		acc, err := db.FindAccount("99999")
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		trs, err := db.FindTransactions(waukeen.TransactionsDBOptions{Accounts: []string{acc.ID}})
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		if len(trs) != 1 || trs[0].Alias != "Visa Alias" || trs[0].AccountNumber != "99999" {
+			t.Errorf("wants account rule to be applied, got %+v", trs)
+		}
+	})
+}
+
+func TestImports(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	imp := &waukeen.Import{Filename: "bank.ofx", Importer: "ofx"}
+	stmt := waukeen.Statement{
+		Account: waukeen.Account{Number: "12345"},
+		Import:  imp,
+		Transactions: []waukeen.Transaction{
+			{FITID: "01", Title: "First", Tags: []string{"food"}},
+			{FITID: "02", Title: "Second"},
+		},
+	}
+
+	err := db.CreateStatement(stmt, nil)
+	if err != nil {
+		t.Fatalf("wants no error, got %s", err)
+	}
+
+	if imp.ID == "" || imp.Created != 2 || imp.Skipped != 0 {
+		t.Errorf("wants import with 2 created transactions, got %+v", imp)
+	}
+
+	again := &waukeen.Import{Filename: "bank.ofx", Importer: "ofx"}
+	stmt.Import = again
+	stmt.Transactions = append(stmt.Transactions,
+		waukeen.Transaction{FITID: "03", Title: "Third"})
+
+	err = db.CreateStatement(stmt, nil)
+	if err != nil {
+		t.Fatalf("wants no error, got %s", err)
+	}
+
+	if again.Created != 1 || again.Skipped != 2 {
+		t.Errorf("wants import with 1 created and 2 skipped, got %+v", again)
+	}
+
+	t.Run("Find Imports", func(t *testing.T) {
+		imports, err := db.FindImports()
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(imports) != 2 || imports[0].ID != again.ID {
+			t.Fatalf("wants latest import first, got %+v", imports)
+		}
+		got := imports[1]
+		if got.Filename != "bank.ofx" || got.Importer != "ofx" || got.AccountID != "1" ||
+			got.Created != 2 || got.Date.IsZero() {
+			t.Errorf("wants %+v, got %+v", imp, got)
+		}
+	})
+
+	t.Run("Transactions Link", func(t *testing.T) {
+		tr, err := db.FindTransaction("3")
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if tr.ImportID != again.ID {
+			t.Errorf("wants import id %s, got %s", again.ID, tr.ImportID)
+		}
+	})
+
+	t.Run("Delete Import", func(t *testing.T) {
+		err := db.DeleteImport(imp.ID)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		trs, err := db.FindTransactions(waukeen.TransactionsDBOptions{})
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(trs) != 1 || trs[0].FITID != "03" {
+			t.Errorf("wants only the second import transactions, got %+v", trs)
+		}
+
+		var links int
+		err = db.QueryRow("SELECT COUNT(*) FROM transaction_tags").Scan(&links)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if links != 0 {
+			t.Errorf("wants tag links to be removed, got %d", links)
+		}
+
+		err = db.DeleteImport(imp.ID)
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+}
+
+func TestTransactionEdits(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	acc := &waukeen.Account{Number: "123"}
+	err := db.CreateAccount(acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := &waukeen.Transaction{AccountID: acc.ID, FITID: "1", Title: "DOMINOS"}
+	err = db.CreateTransaction(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+	want := []waukeen.TransactionEdit{
+		{TransactionID: tr.ID, Title: "DOMINOS", Alias: "Dominos", Date: date},
+		{TransactionID: tr.ID, Title: "DOMINOS", Tag: "pizza", Date: date},
+	}
+
+	for i := range want {
+		err := db.CreateTransactionEdit(&want[i])
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+	}
+
+	got, err := db.FindTransactionEdits()
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants %+v, got %+v", want, got)
+	}
+
+	t.Run("Deleted transaction", func(t *testing.T) {
+		err := db.DeleteTransaction(tr.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := db.FindTransactionEdits()
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("wants edits deleted, got %+v", got)
+		}
+	})
+}
+
+func TestTagModel(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	t.Run("Empty", func(t *testing.T) {
+		m, err := db.FindTagModel()
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if m == nil || len(m.Tags) != 0 || len(m.Tokens) != 0 {
+			t.Errorf("wants empty model, got %+v", m)
+		}
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		old := &waukeen.TagModel{
+			Tags:   map[string]int{"rent": 1},
+			Tokens: map[string]map[string]int{"rent": {"landlord": 1}},
+		}
+		err := db.SaveTagModel(old)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		want := &waukeen.TagModel{
+			Tags: map[string]int{"restaurants": 3, "groceries": 2},
+			Tokens: map[string]map[string]int{
+				"restaurants": {"pizza": 2, "amount:out:25": 3},
+				"groceries":   {"loblaws": 1},
+			},
+		}
+		err = db.SaveTagModel(want)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		got, err := db.FindTagModel()
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("wants %+v, got %+v", want, got)
+		}
+	})
+}
+
+func TestEnvelopeLedger(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	jan := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	assignments := []*waukeen.EnvelopeAssignment{
+		{Tag: "food", Month: jan.AddDate(0, 0, 10), Amount: 20000},
+		{Tag: "food", Month: jan, Amount: 30000},
+		{Tag: "rent", Month: feb, Amount: 150000},
+	}
+	for _, a := range assignments {
+		err := db.SetEnvelopeAssignment(a)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+	}
+
+	move := &waukeen.EnvelopeMove{From: "food", To: "fun", Month: feb, Amount: 5000}
+	err := db.CreateEnvelopeMove(move)
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+
+	t.Run("Same envelope move", func(t *testing.T) {
+		err := db.CreateEnvelopeMove(&waukeen.EnvelopeMove{From: "fun", To: "fun"})
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		l, err := db.FindEnvelopeLedger()
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		var got []waukeen.EnvelopeAssignment
+		for _, a := range l.Assignments {
+			a.ID = ""
+			got = append(got, a)
+		}
+		want := []waukeen.EnvelopeAssignment{
+			{Tag: "food", Month: jan, Amount: 30000},
+			{Tag: "rent", Month: feb, Amount: 150000},
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("wants %+v, got %+v", want, got)
+		}
+
+		moves := []waukeen.EnvelopeMove{*move}
+		if !reflect.DeepEqual(moves, l.Moves) {
+			t.Errorf("wants %+v, got %+v", moves, l.Moves)
+		}
+	})
+
+	t.Run("Delete move", func(t *testing.T) {
+		err := db.DeleteEnvelopeMove(move.ID)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		err = db.DeleteEnvelopeMove(move.ID)
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+}
+
+func TestWithTx(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	t.Run("Rollback", func(t *testing.T) {
+		err := db.WithTx(func(tx waukeen.Database) error {
+			err := tx.CreateAccount(&waukeen.Account{Number: "12345"})
+			if err != nil {
+				return err
+			}
+			return tx.CreateAccount(&waukeen.Account{})
+		})
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+
+		accs, err := db.FindAccounts()
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(accs) != 0 {
+			t.Errorf("wants no accounts, got %+v", accs)
+		}
+	})
+
+	t.Run("Commit", func(t *testing.T) {
+		err := db.WithTx(func(tx waukeen.Database) error {
+			err := tx.CreateAccount(&waukeen.Account{Number: "12345"})
+			if err != nil {
+				return err
+			}
+			return tx.CreateStatement(waukeen.Statement{
+				Account: waukeen.Account{Number: "67890"},
+			}, &mock.TransactionTransformer{})
+		})
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		accs, err := db.FindAccounts()
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if len(accs) != 2 {
+			t.Errorf("wants 2 accounts, got %+v", accs)
+		}
+	})
+}
+
+func TestCreateTag(t *testing.T) {
 	db, path := testDB()
 	defer os.Remove(path)
 
@@ -704,6 +1430,12 @@ func TestUpdateTag(t *testing.T) {
 	}
 
 	want.Name = "food"
+	want.Rollover = true
+	want.RolloverCap = 5000
+	want.Budgets = []waukeen.TagBudget{
+		{Month: time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC), Amount: 4000},
+		{Month: time.Date(2017, 12, 1, 0, 0, 0, 0, time.UTC), Amount: 0},
+	}
 	err = db.UpdateTag(want)
 	if err != nil {
 		t.Errorf("wants no error, got %s", err)
@@ -717,6 +1449,99 @@ func TestUpdateTag(t *testing.T) {
 	if !reflect.DeepEqual(want, got) {
 		t.Errorf("wants %+v, got %+v", want, got)
 	}
+
+	want.Budgets = want.Budgets[1:]
+	err = db.UpdateTag(want)
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+
+	tags, err := db.AllTags()
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+
+	if !reflect.DeepEqual([]waukeen.Tag{*want}, tags) {
+		t.Errorf("wants %+v, got %+v", []waukeen.Tag{*want}, tags)
+	}
+}
+
+func TestTagParent(t *testing.T) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	pizza := &waukeen.Tag{Name: "pizza", Parent: "restaurants"}
+	err := db.CreateTag(pizza)
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+
+	restaurants, err := db.FindTag("restaurants")
+	if err != nil {
+		t.Fatalf("wants parent created, got %s", err)
+	}
+
+	t.Run("Find", func(t *testing.T) {
+		got, err := db.FindTag("pizza")
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if !reflect.DeepEqual(pizza, got) {
+			t.Errorf("wants %+v, got %+v", pizza, got)
+		}
+	})
+
+	t.Run("Own parent", func(t *testing.T) {
+		restaurants.Parent = "restaurants"
+		err := db.UpdateTag(restaurants)
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+
+	t.Run("Below a descendant", func(t *testing.T) {
+		restaurants.Parent = "pizza"
+		err := db.UpdateTag(restaurants)
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
+
+	t.Run("Move", func(t *testing.T) {
+		restaurants.Parent = "food"
+		err := db.UpdateTag(restaurants)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		got, err := db.FindTags("")
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		want := []waukeen.Tag{
+			*restaurants,
+			*pizza,
+			{ID: "3", Name: "food"},
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("wants %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("Delete parent", func(t *testing.T) {
+		err := db.DeleteTag(restaurants.ID)
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+
+		got, err := db.FindTag("pizza")
+		if err != nil {
+			t.Errorf("wants no error, got %s", err)
+		}
+		if got.Parent != "" {
+			t.Errorf("wants no parent, got %s", got.Parent)
+		}
+	})
 }
 
 func TestAllTags(t *testing.T) {
@@ -724,17 +1549,18 @@ func TestAllTags(t *testing.T) {
 	defer os.Remove(path)
 
 	t1 := waukeen.Tag{ID: "1", Name: "foo", MonthlyBudget: 500}
-	t2 := waukeen.Tag{ID: "2", Name: "bar", MonthlyBudget: 100}
-	t3 := waukeen.Tag{ID: "3", Name: "baz", MonthlyBudget: 0}
+	t2 := waukeen.Tag{ID: "2", Name: "bar", MonthlyBudget: 100, Rollover: true}
+	t3 := waukeen.Tag{ID: "3", Name: "baz", MonthlyBudget: 0, Rollover: true, RolloverCap: 50}
+	t4 := waukeen.Tag{ID: "4", Name: "salary", MonthlyBudget: 300000, Income: true}
 
-	for _, tag := range []waukeen.Tag{t1, t2, t3} {
+	for _, tag := range []waukeen.Tag{t1, t2, t3, t4} {
 		err := db.CreateTag(&tag)
 		if err != nil {
 			t.Errorf("wants no error, got %s", err)
 		}
 	}
 
-	want := []waukeen.Tag{t1, t2, t3}
+	want := []waukeen.Tag{t1, t2, t3, t4}
 	got, err := db.AllTags()
 	if err != nil {
 		t.Errorf("wants no error, got %s", err)
@@ -780,3 +1606,54 @@ func TestFindTags(t *testing.T) {
 		}
 	})
 }
+
+func BenchmarkFindTransactions(b *testing.B) {
+	db, path := testDB()
+	defer os.Remove(path)
+
+	acc := testAccount(db)
+	tags := []string{"groceries", "restaurants", "transportation", "rent", "gifts"}
+	date := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err := db.WithTx(func(tx waukeen.Database) error {
+		for i := 0; i < 50000; i++ {
+			t := &waukeen.Transaction{
+				AccountID: acc.ID,
+				FITID:     strconv.Itoa(i),
+				Type:      waukeen.Debit,
+				Title:     "transaction " + strconv.Itoa(i),
+				Amount:    int64(i),
+				Date:      date.Add(time.Duration(i) * 10 * time.Minute),
+				Tags:      []string{tags[i%len(tags)], tags[(i+1)%len(tags)]},
+			}
+			err := tx.CreateTransaction(t)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	opts := []struct {
+		name string
+		opts waukeen.TransactionsDBOptions
+	}{
+		{"All", waukeen.TransactionsDBOptions{}},
+		{"Any Tags", waukeen.TransactionsDBOptions{Tags: []string{"rent", "gifts"}}},
+		{"All Tags", waukeen.TransactionsDBOptions{Tags: []string{"rent", "gifts"}, MatchAllTags: true}},
+	}
+
+	for _, o := range opts {
+		b.Run(o.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := db.FindTransactions(o.opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package transformer
 
 import (
-	"regexp"
 	"strings"
 
 	"github.com/luizbranco/waukeen"
@@ -9,18 +8,36 @@ import (
 
 type Text struct{}
 
-func (Text) Transform(t *waukeen.Transaction, r waukeen.Rule) {
-	re := regexp.MustCompile(`(?i)(^|\s)\Q` + r.Match + `\E($|\s)`)
+func (Text) Transform(t *waukeen.Transaction, r waukeen.Rule) bool {
+	if !r.Conditions.Match(t) {
+		return false
+	}
+
+	re, err := r.Pattern()
+	if err != nil {
+		return false
+	}
+
+	target := r.Target(t)
+
 	switch r.Type {
 	case waukeen.ReplaceRule:
-		alias := re.ReplaceAllString(t.Title, "${1}"+r.Result+"${2}")
-		if alias != t.Title {
-			t.Alias = strings.Trim(alias, " ")
+		result := r.Result
+		if r.Mode == waukeen.WordMatch {
+			result = "${1}" + result + "${2}"
+		}
+		alias := re.ReplaceAllString(target, result)
+		if alias == target {
+			return false
 		}
+		t.Alias = strings.Trim(alias, " ")
 	case waukeen.TagRule:
-		if !re.MatchString(t.Title) {
-			return
+		if !re.MatchString(target) {
+			return false
 		}
 		t.AddTags(r.Result)
+	default:
+		return false
 	}
+	return true
 }
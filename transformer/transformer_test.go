@@ -50,6 +50,72 @@ func TestTextTransform(t *testing.T) {
 			waukeen.Transaction{Title: "Pizzahut", Tags: []string{"pizza"}},
 			waukeen.Rule{Type: waukeen.TagRule, Match: "pizzahut", Result: "pizza"},
 		},
+		{
+			waukeen.Transaction{Title: "SQ *BLUE BOTTLE 0042"},
+			waukeen.Transaction{Title: "SQ *BLUE BOTTLE 0042", Alias: "BLUE BOTTLE"},
+			waukeen.Rule{Type: waukeen.ReplaceRule, Mode: waukeen.RegexMatch,
+				Match: `^sq \*(\w+) (\w+) \d+$`, Result: "${1} ${2}"},
+		},
+		{
+			waukeen.Transaction{Title: "AMZN Mktp CA*2K4"},
+			waukeen.Transaction{Title: "AMZN Mktp CA*2K4", Tags: []string{"shopping"}},
+			waukeen.Rule{Type: waukeen.TagRule, Mode: waukeen.RegexMatch,
+				Match: `amzn mktp (ca|us)`, Result: "shopping"},
+		},
+		{
+			waukeen.Transaction{Title: "Invalid ("},
+			waukeen.Transaction{Title: "Invalid ("},
+			waukeen.Rule{Type: waukeen.TagRule, Mode: waukeen.RegexMatch,
+				Match: `(`, Result: "broken"},
+		},
+		{
+			waukeen.Transaction{Title: "UBER TRIP 8YJQ"},
+			waukeen.Transaction{Title: "UBER TRIP 8YJQ", Alias: "Uber 8YJQ"},
+			waukeen.Rule{Type: waukeen.ReplaceRule, Mode: waukeen.PrefixMatch,
+				Match: "uber trip", Result: "Uber"},
+		},
+		{
+			waukeen.Transaction{Title: "MY UBER TRIP"},
+			waukeen.Transaction{Title: "MY UBER TRIP"},
+			waukeen.Rule{Type: waukeen.TagRule, Mode: waukeen.PrefixMatch,
+				Match: "uber", Result: "transportation"},
+		},
+		{
+			waukeen.Transaction{Title: "NETFLIX.COM 866-579"},
+			waukeen.Transaction{Title: "NETFLIX.COM 866-579", Tags: []string{"entertainment"}},
+			waukeen.Rule{Type: waukeen.TagRule, Mode: waukeen.SuffixMatch,
+				Match: "866-579", Result: "entertainment"},
+		},
+		{
+			waukeen.Transaction{Title: "POS PURCHASE", Description: "LOBLAWS #1012"},
+			waukeen.Transaction{Title: "POS PURCHASE", Description: "LOBLAWS #1012", Tags: []string{"groceries"}},
+			waukeen.Rule{Type: waukeen.TagRule, Field: waukeen.DescriptionField,
+				Match: "loblaws", Result: "groceries"},
+		},
+		{
+			waukeen.Transaction{Title: "LOBLAWS", Description: "POS PURCHASE"},
+			waukeen.Transaction{Title: "LOBLAWS", Description: "POS PURCHASE"},
+			waukeen.Rule{Type: waukeen.TagRule, Field: waukeen.DescriptionField,
+				Match: "loblaws", Result: "groceries"},
+		},
+		{
+			waukeen.Transaction{Title: "POS PURCHASE", Description: "LOBLAWS #1012"},
+			waukeen.Transaction{Title: "POS PURCHASE", Description: "LOBLAWS #1012", Alias: "Loblaws"},
+			waukeen.Rule{Type: waukeen.ReplaceRule, Mode: waukeen.RegexMatch,
+				Field: waukeen.DescriptionField, Match: `^loblaws.*`, Result: "Loblaws"},
+		},
+		{
+			waukeen.Transaction{Title: "AMAZON.CA", Type: waukeen.Debit, Amount: -64999},
+			waukeen.Transaction{Title: "AMAZON.CA", Type: waukeen.Debit, Amount: -64999, Tags: []string{"electronics"}},
+			waukeen.Rule{Type: waukeen.TagRule, Mode: waukeen.PrefixMatch, Match: "amazon", Result: "electronics",
+				Conditions: &waukeen.RuleConditions{MinAmount: 50000, TransactionType: waukeen.Debit}},
+		},
+		{
+			waukeen.Transaction{Title: "AMAZON.CA", Type: waukeen.Debit, Amount: -2599},
+			waukeen.Transaction{Title: "AMAZON.CA", Type: waukeen.Debit, Amount: -2599},
+			waukeen.Rule{Type: waukeen.TagRule, Mode: waukeen.PrefixMatch, Match: "amazon", Result: "electronics",
+				Conditions: &waukeen.RuleConditions{MinAmount: 50000, TransactionType: waukeen.Debit}},
+		},
 	}
 
 	for _, eg := range egs {
@@ -62,3 +128,27 @@ func TestTextTransform(t *testing.T) {
 		}
 	}
 }
+
+func TestTextTransformMatched(t *testing.T) {
+	txt := Text{}
+	tr := &waukeen.Transaction{Title: "UBER TRIP"}
+
+	egs := []struct {
+		rule waukeen.Rule
+		want bool
+	}{
+		{waukeen.Rule{Type: waukeen.TagRule, Match: "uber", Result: "transportation"}, true},
+		{waukeen.Rule{Type: waukeen.TagRule, Match: "lyft", Result: "transportation"}, false},
+		{waukeen.Rule{Type: waukeen.ReplaceRule, Match: "trip", Result: ""}, true},
+		{waukeen.Rule{Type: waukeen.ReplaceRule, Match: "eats", Result: "Eats"}, false},
+		{waukeen.Rule{Type: waukeen.TagRule, Match: "uber", Result: "transportation",
+			Conditions: &waukeen.RuleConditions{MinAmount: 100}}, false},
+	}
+
+	for _, eg := range egs {
+		got := txt.Transform(tr, eg.rule)
+		if got != eg.want {
+			t.Errorf("wants %t for %+v, got %t", eg.want, eg.rule, got)
+		}
+	}
+}
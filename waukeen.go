@@ -1,13 +1,21 @@
 package waukeen
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 )
 
 type AccountType int
 type TransactionType int
 type RuleType int
+type MatchMode int
+type RuleField int
 
 const (
 	OtherAccount AccountType = iota
@@ -29,61 +37,351 @@ const (
 	TagRule
 )
 
+const (
+	WordMatch MatchMode = iota
+	RegexMatch
+	PrefixMatch
+	SuffixMatch
+)
+
+const (
+	TitleField RuleField = iota
+	DescriptionField
+)
+
 type Account struct {
-	ID       string
-	Number   string
-	Name     string
-	Type     AccountType
-	Currency string
-	Balance  int64
+	ID       string      `json:"id"`
+	Number   string      `json:"number"`
+	Name     string      `json:"name"`
+	Type     AccountType `json:"type"`
+	Currency string      `json:"currency"`
+	Balance  int64       `json:"balance"`
 }
 
 type Transaction struct {
-	ID          string
-	AccountID   string
-	FITID       string
-	Type        TransactionType
-	Title       string
-	Alias       string
-	Description string
-	Amount      int64
-	Date        time.Time
-	Tags        []string
+	ID          string          `json:"id"`
+	AccountID   string          `json:"account_id"`
+	FITID       string          `json:"fitid"`
+	Type        TransactionType `json:"type"`
+	Title       string          `json:"title"`
+	Alias       string          `json:"alias"`
+	Description string          `json:"description"`
+	Amount      int64           `json:"amount"`
+	Date        time.Time       `json:"date"`
+	Tags        []string        `json:"tags"`
+	ImportID    string          `json:"import_id,omitempty"`
+	// AliasEdited and TagsEdited protect values set by hand from being
+	// overwritten when rules are applied again.
+	AliasEdited bool `json:"alias_edited,omitempty"`
+	TagsEdited  bool `json:"tags_edited,omitempty"`
+	// AccountNumber is the number of the account of AccountID, it is only
+	// read and saving a transaction ignores it.
+	AccountNumber string `json:"account_number,omitempty"`
 }
 
+// Tag budgets with Rollover carry what is left of a month, or what was
+// overspent, into the next one. A RolloverCap limits the amount carried
+// either way, zero carries everything. Budgets plan a different amount than
+// MonthlyBudget for some months. Credits tagged with an Income tag are income,
+// its budget the income expected, other credits are refunds. A tag with a
+// Parent rolls up into it.
 type Tag struct {
-	ID            string
-	Name          string
-	MonthlyBudget int64
+	ID            string      `json:"id"`
+	Name          string      `json:"name"`
+	MonthlyBudget int64       `json:"monthly_budget"`
+	Rollover      bool        `json:"rollover"`
+	RolloverCap   int64       `json:"rollover_cap,omitempty"`
+	Budgets       []TagBudget `json:"budgets,omitempty"`
+	Income        bool        `json:"income"`
+	Parent        string      `json:"parent,omitempty"`
+}
+
+// TagBudget is the amount planned for a tag in the month of Month.
+type TagBudget struct {
+	Month  time.Time `json:"month"`
+	Amount int64     `json:"amount"`
+}
+
+// Budget is the amount planned for the month of m, MonthlyBudget unless the
+// month has its own.
+func (t Tag) Budget(m time.Time) int64 {
+	for _, b := range t.Budgets {
+		if b.Month.Year() == m.Year() && b.Month.Month() == m.Month() {
+			return b.Amount
+		}
+	}
+	return t.MonthlyBudget
+}
+
+// TagTree is the hierarchy of tags by their Parent. Tags with an unknown
+// parent, or whose parents lead back to them, are roots.
+type TagTree struct {
+	tags     map[string]Tag
+	children map[string][]string
+}
+
+func NewTagTree(tags []Tag) *TagTree {
+	t := &TagTree{
+		tags:     make(map[string]Tag),
+		children: make(map[string][]string),
+	}
+
+	for _, tag := range tags {
+		t.tags[tag.Name] = tag
+	}
+
+	for _, tag := range tags {
+		parent := tag.Parent
+		if _, ok := t.tags[parent]; !ok || t.cycles(tag.Name) {
+			parent = ""
+		}
+		t.children[parent] = append(t.children[parent], tag.Name)
+	}
+
+	for _, names := range t.children {
+		sort.Strings(names)
+	}
+
+	return t
 }
 
+func (t *TagTree) cycles(name string) bool {
+	seen := make(map[string]bool)
+	for p := t.tags[name].Parent; p != ""; p = t.tags[p].Parent {
+		if p == name {
+			return true
+		}
+		if seen[p] {
+			return false
+		}
+		seen[p] = true
+	}
+	return false
+}
+
+// Tag finds a tag of the tree by name.
+func (t *TagTree) Tag(name string) (Tag, bool) {
+	tag, ok := t.tags[name]
+	return tag, ok
+}
+
+// Children of the tag sorted by name, the roots for an empty name.
+func (t *TagTree) Children(name string) []string {
+	return t.children[name]
+}
+
+// Ancestors of the tag, its parent first.
+func (t *TagTree) Ancestors(name string) []string {
+	if t.cycles(name) {
+		return nil
+	}
+
+	var list []string
+	seen := map[string]bool{name: true}
+	for p := t.tags[name].Parent; p != "" && !seen[p]; p = t.tags[p].Parent {
+		if _, ok := t.tags[p]; !ok {
+			break
+		}
+		seen[p] = true
+		list = append(list, p)
+	}
+	return list
+}
+
+// Descendants of the tag, depth first.
+func (t *TagTree) Descendants(name string) []string {
+	var list []string
+	seen := map[string]bool{name: true}
+
+	var walk func(string)
+	walk = func(name string) {
+		for _, c := range t.children[name] {
+			if !seen[c] {
+				seen[c] = true
+				list = append(list, c)
+				walk(c)
+			}
+		}
+	}
+	walk(name)
+
+	return list
+}
+
+// Budget of a tag, Spent is net of refunds. Income tags have what was
+// Expected and the Income received instead of a plan.
 type Budget struct {
 	Tag          string
+	Parent       string
 	Transactions int
 	Planned      int64
 	Spent        int64
+	Expected     int64
+	Income       int64
+	// Carry is what rolls over past the last month, only for rollover tags.
+	Carry  int64
+	Months []BudgetMonth
 }
 
-type Rule struct {
+// BudgetMonth is a month of a Budget, Carry is what rolled over from the
+// month before.
+type BudgetMonth struct {
+	Month    time.Time
+	Planned  int64
+	Spent    int64
+	Expected int64
+	Income   int64
+	Carry    int64
+}
+
+// Left is what remains to spend in the month, negative when overspent.
+func (m BudgetMonth) Left() int64 {
+	return m.Planned + m.Carry - m.Spent
+}
+
+// BudgetTotals is the income and the spending, net of refunds, of a month.
+type BudgetTotals struct {
+	Month    time.Time
+	Expected int64
+	Income   int64
+	Expenses int64
+}
+
+// Saved is the income not spent, negative when spending more than earning.
+func (t BudgetTotals) Saved() int64 {
+	return t.Income - t.Expenses
+}
+
+// SavingsRate is the share of the income saved, zero without income.
+func (t BudgetTotals) SavingsRate() float64 {
+	if t.Income <= 0 {
+		return 0
+	}
+	return float64(t.Saved()) / float64(t.Income)
+}
+
+// EnvelopeAssignment is money given to the envelope of a tag in the month of
+// Month, taken from what is to be assigned.
+type EnvelopeAssignment struct {
+	ID     string
+	Tag    string
+	Month  time.Time
+	Amount int64
+}
+
+// EnvelopeMove is money taken from the envelope of a tag and put in another
+// one in the month of Month.
+type EnvelopeMove struct {
 	ID     string
-	Type   RuleType
-	Match  string
-	Result string
+	From   string
+	To     string
+	Month  time.Time
+	Amount int64
+}
+
+// EnvelopeLedger is every assignment and move made to envelopes.
+type EnvelopeLedger struct {
+	Assignments []EnvelopeAssignment
+	Moves       []EnvelopeMove
+}
+
+// Envelope is the money of a tag in a month. Carry is what was left in it
+// from the months before, Moved what was moved in, negative when moved out.
+type Envelope struct {
+	Tag      string
+	Carry    int64
+	Assigned int64
+	Moved    int64
+	Spent    int64
+}
+
+// Balance is what is left in the envelope, negative when overspent.
+func (e Envelope) Balance() int64 {
+	return e.Carry + e.Assigned + e.Moved - e.Spent
+}
+
+// EnvelopeMonth is the state of every envelope in a month. ToBeAssigned is
+// the income received until the end of the month not assigned yet.
+type EnvelopeMonth struct {
+	Month        time.Time
+	Income       int64
+	Assigned     int64
+	ToBeAssigned int64
+	Envelopes    []Envelope
+}
+
+type Rule struct {
+	ID         string          `json:"id,omitempty"`
+	Type       RuleType        `json:"type"`
+	Mode       MatchMode       `json:"mode,omitempty"`
+	Field      RuleField       `json:"field,omitempty"`
+	Match      string          `json:"match"`
+	Result     string          `json:"result"`
+	Conditions *RuleConditions `json:"conditions,omitempty"`
+	Priority   int             `json:"priority,omitempty"`
+	Stop       bool            `json:"stop,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// RuleConditions restrict a rule to matching transactions, zero values are
+// ignored. Amounts are absolute values in cents so MinAmount 50000 holds for
+// both a $500 debit and a $500 credit. Accounts are account numbers, which
+// unlike ids are the same in every database rules are exported to. A day
+// window wraps around the end of the month when FirstDay is after LastDay.
+type RuleConditions struct {
+	MinAmount       int64           `json:"min_amount,omitempty"`
+	MaxAmount       int64           `json:"max_amount,omitempty"`
+	Accounts        []string        `json:"accounts,omitempty"`
+	TransactionType TransactionType `json:"transaction_type,omitempty"`
+	Weekdays        []time.Weekday  `json:"weekdays,omitempty"`
+	FirstDay        int             `json:"first_day,omitempty"`
+	LastDay         int             `json:"last_day,omitempty"`
 }
 
 type RulesImporter interface {
 	Import(io.Reader) ([]Rule, error)
 }
 
+// RulesExporter writes rules in a format RulesImporter reads back, keeping
+// their order.
+type RulesExporter interface {
+	Export(io.Writer, []Rule) error
+}
+
 type Statement struct {
 	Account      Account
 	Transactions []Transaction
+	Import       *Import
+}
+
+type Import struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename"`
+	Importer  string    `json:"importer"`
+	AccountID string    `json:"account_id"`
+	Date      time.Time `json:"date"`
+	Created   int       `json:"created"`
+	Skipped   int       `json:"skipped"`
 }
 
 type StatementsImporter interface {
 	Import(io.Reader) ([]Statement, error)
 }
 
+// AccountStatementsImporter imports files that may not say which account
+// they are from. WithAccount returns an importer that uses the account number
+// for them.
+type AccountStatementsImporter interface {
+	StatementsImporter
+	WithAccount(number string) StatementsImporter
+}
+
+type StatementsImporters interface {
+	Formats() []string
+	Find(format, filename string, head []byte) (string, StatementsImporter, error)
+}
+
 type Database interface {
 	CreateAccount(*Account) error
 	UpdateAccount(*Account) error
@@ -97,9 +395,14 @@ type Database interface {
 	FindTransaction(id string) (*Transaction, error)
 	FindTransactions(TransactionsDBOptions) ([]Transaction, error)
 
+	// CreateRule appends rules without a priority after every other rule.
 	CreateRule(*Rule) error
+	UpdateRule(*Rule) error
 	DeleteRule(id string) error
+	// FindRules returns rules by ascending priority, then by creation.
 	FindRules(ids ...string) ([]Rule, error)
+	// ReorderRules sets the priority of rules to their position in ids.
+	ReorderRules(ids []string) error
 
 	AllTags() ([]Tag, error)
 	CreateTag(*Tag) error
@@ -108,23 +411,133 @@ type Database interface {
 	FindTag(name string) (*Tag, error)
 	FindTags(starts string) ([]Tag, error)
 
+	// CreateStatement applies every rule to new transactions through the
+	// transformer, a nil transformer imports them as they are. A statement
+	// Import is recorded with the number of transactions created and skipped.
 	CreateStatement(Statement, TransactionTransformer) error
+
+	FindImports() ([]Import, error)
+	DeleteImport(id string) error
+
+	CreateTransactionEdit(*TransactionEdit) error
+	// FindTransactionEdits returns every edit, oldest first.
+	FindTransactionEdits() ([]TransactionEdit, error)
+
+	// FindTagModel returns an empty model when none was saved yet.
+	FindTagModel() (*TagModel, error)
+	// SaveTagModel replaces the saved model.
+	SaveTagModel(*TagModel) error
+
+	// SetEnvelopeAssignment replaces what was assigned to the tag envelope
+	// in the month.
+	SetEnvelopeAssignment(*EnvelopeAssignment) error
+	CreateEnvelopeMove(*EnvelopeMove) error
+	DeleteEnvelopeMove(id string) error
+	FindEnvelopeLedger() (*EnvelopeLedger, error)
+
+	WithTx(func(Database) error) error
 }
 
 type TransactionsDBOptions struct {
 	Accounts []string
+	FITIDs   []string
 	Types    []TransactionType
 	Start    time.Time
 	End      time.Time
 	Tags     []string
+	// MatchAllTags only finds transactions tagged with every one of Tags
+	// instead of any of them.
+	MatchAllTags bool
+	// DescendantTags matches a tag of Tags by any tag below it too.
+	DescendantTags bool
 }
 
 type TransactionTransformer interface {
-	Transform(*Transaction, Rule)
+	// Transform applies the rule to the transaction when it matches,
+	// reporting whether it did.
+	Transform(*Transaction, Rule) bool
+}
+
+// TransactionEdit records an alias or a tag given to a transaction by hand,
+// only one of Alias or Tag is set. Title is the transaction title when the
+// edit was made.
+type TransactionEdit struct {
+	ID            string
+	TransactionID string
+	Title         string
+	Alias         string
+	Tag           string
+	Date          time.Time
+}
+
+// TagModel is what a TagClassifier learned from tagged transactions: how
+// many transactions had each tag and how many of those had each token.
+type TagModel struct {
+	Tags   map[string]int
+	Tokens map[string]map[string]int
+}
+
+type TagSuggestion struct {
+	Tag        string  `json:"tag"`
+	Confidence float64 `json:"confidence"`
+	// Confident suggestions reach the classifier threshold and are applied
+	// on import.
+	Confident bool `json:"confident"`
+}
+
+// TagClassifier is a TransactionTransformer that also learns tags from
+// transactions already tagged to suggest them for others.
+type TagClassifier interface {
+	TransactionTransformer
+	// Train builds a model from the tagged transactions, Load starts using
+	// it for suggestions.
+	Train([]Transaction) *TagModel
+	Load(*TagModel)
+	// Suggest lists tags the transaction doesn't have yet by descending
+	// confidence.
+	Suggest(*Transaction) []TagSuggestion
+}
+
+// ApplyRules runs the rules through the transformer in the given order,
+// which is ascending priority as returned by Database.FindRules. A replace
+// rule overrides the alias set by an earlier one and tags accumulate, until
+// a matching rule flagged with Stop ends the evaluation. When the
+// transformer is a TagClassifier, transactions the rules left untagged get
+// its confident suggestions.
+func ApplyRules(t *Transaction, rules []Rule, transformer TransactionTransformer) {
+	for _, r := range rules {
+		if transformer.Transform(t, r) && r.Stop {
+			break
+		}
+	}
+
+	c, ok := transformer.(TagClassifier)
+	if !ok || len(t.Tags) > 0 {
+		return
+	}
+
+	for _, s := range c.Suggest(t) {
+		if s.Confident {
+			t.AddTags(s.Tag)
+		}
+	}
 }
 
 type BudgetCalculator interface {
-	Calculate(Months int, trs []Transaction, tags []Tag) []Budget
+	// Calculate budgets the months from start, carrying over amounts for
+	// rollover tags from the first month on.
+	Calculate(start time.Time, months int, trs []Transaction, tags []Tag) []Budget
+	// Totals sums the income and spending of every month from start, counting
+	// each transaction once whatever its tags.
+	Totals(start time.Time, months int, trs []Transaction, tags []Tag) []BudgetTotals
+}
+
+// EnvelopeCalculator does zero-based budgeting: income is to be assigned to
+// tag envelopes and spending draws them down.
+type EnvelopeCalculator interface {
+	// Envelopes computes the envelopes of the month from every transaction
+	// and ledger entry until its end.
+	Envelopes(month time.Time, trs []Transaction, tags []Tag, l EnvelopeLedger) EnvelopeMonth
 }
 
 func (t *Transaction) AddTags(tags ...string) {
@@ -173,6 +586,16 @@ func (t RuleType) String() string {
 	return "Unknown"
 }
 
+func (t RuleType) MarshalJSON() ([]byte, error) {
+	switch t {
+	case ReplaceRule:
+		return []byte(`"replace"`), nil
+	case TagRule:
+		return []byte(`"tag"`), nil
+	}
+	return []byte(`"unknown"`), nil
+}
+
 func (t *RuleType) UnmarshalJSON(data []byte) error {
 	switch string(data) {
 	case `"replace"`:
@@ -184,3 +607,252 @@ func (t *RuleType) UnmarshalJSON(data []byte) error {
 	}
 	return nil
 }
+
+var matchModes = map[MatchMode]string{
+	WordMatch:   "word",
+	RegexMatch:  "regex",
+	PrefixMatch: "prefix",
+	SuffixMatch: "suffix",
+}
+
+var ruleFields = map[RuleField]string{
+	TitleField:       "title",
+	DescriptionField: "description",
+}
+
+func (m MatchMode) String() string {
+	return matchModes[m]
+}
+
+func (m MatchMode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+func (m *MatchMode) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	for mode, name := range matchModes {
+		if name == s {
+			*m = mode
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid rule match mode %q", s)
+}
+
+func (f RuleField) String() string {
+	return ruleFields[f]
+}
+
+func (f RuleField) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.String())
+}
+
+func (f *RuleField) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	for field, name := range ruleFields {
+		if name == s {
+			*f = field
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid rule field %q", s)
+}
+
+// Pattern compiles the case insensitive expression a rule matches with. Word
+// matches capture the surrounding whitespace in the first and last groups.
+func (r Rule) Pattern() (*regexp.Regexp, error) {
+	if r.pattern != nil {
+		return r.pattern, nil
+	}
+
+	m := regexp.QuoteMeta(r.Match)
+	switch r.Mode {
+	case WordMatch:
+		return regexp.Compile(`(?i)(^|\s)` + m + `($|\s)`)
+	case RegexMatch:
+		return regexp.Compile(`(?i)` + r.Match)
+	case PrefixMatch:
+		return regexp.Compile(`(?i)^\s*` + m)
+	case SuffixMatch:
+		return regexp.Compile(`(?i)` + m + `\s*$`)
+	}
+	return nil, fmt.Errorf("invalid rule match mode %d", r.Mode)
+}
+
+// CompileRules returns a copy of the rules with their patterns compiled, so
+// running them over many transactions compiles each pattern only once.
+func CompileRules(rules []Rule) []Rule {
+	compiled := make([]Rule, len(rules))
+	for i, r := range rules {
+		r.pattern, _ = r.Pattern()
+		compiled[i] = r
+	}
+	return compiled
+}
+
+// Target returns the transaction text the rule is matched against.
+func (r Rule) Target(t *Transaction) string {
+	if r.Field == DescriptionField {
+		return t.Description
+	}
+	return t.Title
+}
+
+// Validate rejects rules that could never be applied, such as unknown types
+// or invalid regular expressions.
+func (r Rule) Validate() error {
+	if r.Type != ReplaceRule && r.Type != TagRule {
+		return errors.New(`rule type must be "replace" or "tag"`)
+	}
+	if r.Match == "" {
+		return errors.New("rule match is required")
+	}
+	if r.Type == TagRule && strings.TrimSpace(r.Result) == "" {
+		return errors.New("tag rule result is required")
+	}
+	if _, ok := ruleFields[r.Field]; !ok {
+		return fmt.Errorf("invalid rule field %d", r.Field)
+	}
+	_, err := r.Pattern()
+	if err != nil {
+		return fmt.Errorf("invalid rule match %q: %s", r.Match, err)
+	}
+	return r.Conditions.Validate()
+}
+
+// Empty reports whether there is no condition to check.
+func (c *RuleConditions) Empty() bool {
+	return c == nil || (c.MinAmount == 0 && c.MaxAmount == 0 &&
+		len(c.Accounts) == 0 && c.TransactionType == OtherTransaction &&
+		len(c.Weekdays) == 0 && c.FirstDay == 0 && c.LastDay == 0)
+}
+
+func (c *RuleConditions) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.MinAmount < 0 || c.MaxAmount < 0 {
+		return errors.New("rule amounts must be absolute values")
+	}
+	if c.MaxAmount != 0 && c.MinAmount > c.MaxAmount {
+		return errors.New("rule min amount is over its max amount")
+	}
+	if c.TransactionType < OtherTransaction || c.TransactionType > Check {
+		return fmt.Errorf("invalid rule transaction type %d", c.TransactionType)
+	}
+	for _, d := range c.Weekdays {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("invalid rule weekday %d", d)
+		}
+	}
+	if c.FirstDay < 0 || c.FirstDay > 31 || c.LastDay < 0 || c.LastDay > 31 {
+		return errors.New("rule days must be between 1 and 31")
+	}
+	return nil
+}
+
+// Match reports whether the transaction holds every condition, nil
+// conditions match any transaction.
+func (c *RuleConditions) Match(t *Transaction) bool {
+	if c == nil {
+		return true
+	}
+
+	amount := t.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+	if amount < c.MinAmount || (c.MaxAmount != 0 && amount > c.MaxAmount) {
+		return false
+	}
+
+	if len(c.Accounts) > 0 && !contains(c.Accounts, t.AccountNumber) {
+		return false
+	}
+
+	if c.TransactionType != OtherTransaction && c.TransactionType != t.Type {
+		return false
+	}
+
+	if len(c.Weekdays) > 0 {
+		found := false
+		for _, d := range c.Weekdays {
+			if t.Date.Weekday() == d {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	day := t.Date.Day()
+	switch {
+	case c.FirstDay == 0 && c.LastDay == 0:
+	case c.LastDay == 0:
+		return day >= c.FirstDay
+	case c.FirstDay == 0:
+		return day <= c.LastDay
+	case c.FirstDay <= c.LastDay:
+		return day >= c.FirstDay && day <= c.LastDay
+	default:
+		return day >= c.FirstDay || day <= c.LastDay
+	}
+
+	return true
+}
+
+func (c *RuleConditions) String() string {
+	if c.Empty() {
+		return ""
+	}
+
+	var list []string
+
+	if c.MinAmount != 0 {
+		list = append(list, fmt.Sprintf("amount >= %d.%02d", c.MinAmount/100, c.MinAmount%100))
+	}
+	if c.MaxAmount != 0 {
+		list = append(list, fmt.Sprintf("amount <= %d.%02d", c.MaxAmount/100, c.MaxAmount%100))
+	}
+	if len(c.Accounts) > 0 {
+		list = append(list, "accounts "+strings.Join(c.Accounts, ", "))
+	}
+	if c.TransactionType != OtherTransaction {
+		list = append(list, c.TransactionType.String())
+	}
+	if len(c.Weekdays) > 0 {
+		var days []string
+		for _, d := range c.Weekdays {
+			days = append(days, d.String()[:3])
+		}
+		list = append(list, strings.Join(days, ", "))
+	}
+	switch {
+	case c.FirstDay != 0 && c.LastDay != 0:
+		list = append(list, fmt.Sprintf("days %d-%d", c.FirstDay, c.LastDay))
+	case c.FirstDay != 0:
+		list = append(list, fmt.Sprintf("from day %d", c.FirstDay))
+	case c.LastDay != 0:
+		list = append(list, fmt.Sprintf("until day %d", c.LastDay))
+	}
+
+	return strings.Join(list, "; ")
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+package waukeen
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRuleValidate(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule Rule
+		err  bool
+	}{
+		{name: "word", rule: Rule{Type: TagRule, Match: "tacos?", Result: "food"}},
+		{name: "regex", rule: Rule{Type: TagRule, Mode: RegexMatch, Match: `^amzn\s`, Result: "shopping"}},
+		{name: "invalid regex", rule: Rule{Type: TagRule, Mode: RegexMatch, Match: "amzn ("}, err: true},
+		{name: "unknown mode", rule: Rule{Type: TagRule, Mode: 9, Match: "uber"}, err: true},
+		{name: "unknown field", rule: Rule{Type: TagRule, Field: 9, Match: "uber"}, err: true},
+		{name: "unknown type", rule: Rule{Match: "uber"}, err: true},
+		{name: "empty match", rule: Rule{Type: ReplaceRule}, err: true},
+		{name: "empty tag", rule: Rule{Type: TagRule, Match: "uber", Result: " "}, err: true},
+		{name: "empty alias", rule: Rule{Type: ReplaceRule, Match: "toronto"}},
+		{name: "conditions", rule: Rule{Type: TagRule, Match: "amazon", Result: "shopping",
+			Conditions: &RuleConditions{MinAmount: 50000, FirstDay: 28, LastDay: 3}}},
+		{name: "invalid amount range", rule: Rule{Type: TagRule, Match: "amazon",
+			Conditions: &RuleConditions{MinAmount: 50000, MaxAmount: 100}}, err: true},
+		{name: "invalid day", rule: Rule{Type: TagRule, Match: "amazon",
+			Conditions: &RuleConditions{LastDay: 32}}, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rule.Validate()
+			if tc.err && err == nil {
+				t.Errorf("wants error, got none")
+			}
+			if !tc.err && err != nil {
+				t.Errorf("wants no error, got %s", err)
+			}
+		})
+	}
+}
+
+func TestCompileRules(t *testing.T) {
+	rules := []Rule{
+		{Type: TagRule, Match: "uber"},
+		{Type: TagRule, Mode: RegexMatch, Match: "amzn ("},
+	}
+
+	compiled := CompileRules(rules)
+
+	if rules[0].pattern != nil {
+		t.Errorf("wants rules given unchanged")
+	}
+
+	re, err := compiled[0].Pattern()
+	if err != nil {
+		t.Fatalf("wants no error, got %s", err)
+	}
+	if re != compiled[0].pattern {
+		t.Errorf("wants compiled pattern to be reused")
+	}
+	if !re.MatchString("UBER TRIP") {
+		t.Errorf("wants pattern to match")
+	}
+
+	_, err = compiled[1].Pattern()
+	if err == nil {
+		t.Errorf("wants error, got none")
+	}
+}
+
+func TestRuleJSON(t *testing.T) {
+	in := Rule{Type: TagRule, Mode: SuffixMatch, Field: DescriptionField,
+		Match: "866-579", Result: "entertainment"}
+
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("wants no error, got %s", err)
+	}
+
+	want := `{"type":"tag","mode":"suffix","field":"description","match":"866-579","result":"entertainment"}`
+	if string(b) != want {
+		t.Errorf("wants %s, got %s", want, b)
+	}
+
+	var out Rule
+	err = json.Unmarshal(b, &out)
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("wants %+v, got %+v", in, out)
+	}
+
+	err = json.Unmarshal([]byte(`{"mode": "fuzzy"}`), &out)
+	if err == nil {
+		t.Errorf("wants error, got none")
+	}
+}
+
+func TestRuleConditionsMatch(t *testing.T) {
+	// Friday 13th
+	date := time.Date(2016, 5, 13, 0, 0, 0, 0, time.UTC)
+	tr := &Transaction{AccountID: "1", AccountNumber: "12345", Type: Debit,
+		Amount: -60000, Date: date}
+
+	testCases := []struct {
+		name  string
+		conds *RuleConditions
+		want  bool
+	}{
+		{name: "no conditions", conds: nil, want: true},
+		{name: "over min amount", conds: &RuleConditions{MinAmount: 50000}, want: true},
+		{name: "under min amount", conds: &RuleConditions{MinAmount: 70000}, want: false},
+		{name: "amount range", conds: &RuleConditions{MinAmount: 50000, MaxAmount: 60000}, want: true},
+		{name: "over max amount", conds: &RuleConditions{MaxAmount: 10000}, want: false},
+		{name: "account", conds: &RuleConditions{Accounts: []string{"67890", "12345"}}, want: true},
+		{name: "other account", conds: &RuleConditions{Accounts: []string{"67890"}}, want: false},
+		{name: "account id", conds: &RuleConditions{Accounts: []string{"1"}}, want: false},
+		{name: "type", conds: &RuleConditions{TransactionType: Debit}, want: true},
+		{name: "other type", conds: &RuleConditions{TransactionType: Credit}, want: false},
+		{name: "weekday", conds: &RuleConditions{Weekdays: []time.Weekday{time.Friday}}, want: true},
+		{name: "other weekday", conds: &RuleConditions{Weekdays: []time.Weekday{time.Monday}}, want: false},
+		{name: "day window", conds: &RuleConditions{FirstDay: 10, LastDay: 15}, want: true},
+		{name: "outside day window", conds: &RuleConditions{FirstDay: 1, LastDay: 5}, want: false},
+		{name: "wrapping day window", conds: &RuleConditions{FirstDay: 28, LastDay: 3}, want: false},
+		{name: "from day", conds: &RuleConditions{FirstDay: 13}, want: true},
+		{name: "until day", conds: &RuleConditions{LastDay: 12}, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.conds.Match(tr)
+			if got != tc.want {
+				t.Errorf("wants %t, got %t", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("wrapping day window end of month", func(t *testing.T) {
+		c := &RuleConditions{FirstDay: 28, LastDay: 3}
+		tr := &Transaction{Date: time.Date(2016, 5, 30, 0, 0, 0, 0, time.UTC)}
+		if !c.Match(tr) {
+			t.Errorf("wants match, got none")
+		}
+	})
+}
+
+type textTransformer struct{}
+
+func (textTransformer) Transform(t *Transaction, r Rule) bool {
+	if t.Title != r.Match {
+		return false
+	}
+	switch r.Type {
+	case ReplaceRule:
+		t.Alias = r.Result
+	case TagRule:
+		t.AddTags(r.Result)
+	}
+	return true
+}
+
+func TestApplyRules(t *testing.T) {
+	rules := []Rule{
+		{Type: ReplaceRule, Match: "DOMINOS", Result: "Dominos"},
+		{Type: TagRule, Match: "DOMINOS", Result: "pizza"},
+		{Type: ReplaceRule, Match: "DOMINOS", Result: "Domino's", Stop: true},
+		{Type: TagRule, Match: "DOMINOS", Result: "restaurants"},
+		{Type: TagRule, Match: "UBER", Result: "transportation", Stop: true},
+	}
+
+	t.Run("Later replace rules win until one stops", func(t *testing.T) {
+		tr := &Transaction{Title: "DOMINOS"}
+		ApplyRules(tr, rules, textTransformer{})
+
+		want := &Transaction{Title: "DOMINOS", Alias: "Domino's", Tags: []string{"pizza"}}
+		if !reflect.DeepEqual(want, tr) {
+			t.Errorf("wants %+v, got %+v", want, tr)
+		}
+	})
+
+	t.Run("Stop only applies when the rule matches", func(t *testing.T) {
+		tr := &Transaction{Title: "DOMINOS"}
+		ApplyRules(tr, append(rules[4:], rules[:2]...), textTransformer{})
+
+		want := &Transaction{Title: "DOMINOS", Alias: "Dominos", Tags: []string{"pizza"}}
+		if !reflect.DeepEqual(want, tr) {
+			t.Errorf("wants %+v, got %+v", want, tr)
+		}
+	})
+
+	t.Run("Classifier tags what rules left untagged", func(t *testing.T) {
+		c := tagClassifier{[]TagSuggestion{
+			{Tag: "restaurants", Confidence: 0.9, Confident: true},
+			{Tag: "groceries", Confidence: 0.1},
+		}}
+
+		tr := &Transaction{Title: "PIZZA PIZZA"}
+		ApplyRules(tr, rules, c)
+
+		want := []string{"restaurants"}
+		if !reflect.DeepEqual(want, tr.Tags) {
+			t.Errorf("wants %v, got %v", want, tr.Tags)
+		}
+
+		tr = &Transaction{Title: "DOMINOS"}
+		ApplyRules(tr, rules, c)
+
+		want = []string{"pizza"}
+		if !reflect.DeepEqual(want, tr.Tags) {
+			t.Errorf("wants %v, got %v", want, tr.Tags)
+		}
+	})
+}
+
+type tagClassifier struct {
+	suggestions []TagSuggestion
+}
+
+func (tagClassifier) Transform(t *Transaction, r Rule) bool {
+	return textTransformer{}.Transform(t, r)
+}
+
+func (tagClassifier) Train([]Transaction) *TagModel { return nil }
+
+func (tagClassifier) Load(*TagModel) {}
+
+func (c tagClassifier) Suggest(*Transaction) []TagSuggestion {
+	return c.suggestions
+}
+
+func TestTagTree(t *testing.T) {
+	tree := NewTagTree([]Tag{
+		{Name: "restaurants", Parent: "food"},
+		{Name: "food"},
+		{Name: "groceries", Parent: "food"},
+		{Name: "pizza", Parent: "restaurants"},
+		{Name: "rent"},
+		{Name: "orphan", Parent: "missing"},
+		{Name: "chicken", Parent: "egg"},
+		{Name: "egg", Parent: "chicken"},
+	})
+
+	tests := []struct {
+		name string
+		want []string
+		got  []string
+	}{
+		{"Roots", []string{"chicken", "egg", "food", "orphan", "rent"}, tree.Children("")},
+		{"Children", []string{"groceries", "restaurants"}, tree.Children("food")},
+		{"Ancestors", []string{"restaurants", "food"}, tree.Ancestors("pizza")},
+		{"Root ancestors", nil, tree.Ancestors("orphan")},
+		{"Descendants", []string{"groceries", "restaurants", "pizza"}, tree.Descendants("food")},
+		{"Leaf descendants", nil, tree.Descendants("pizza")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.want, tt.got) {
+				t.Errorf("wants %v, got %v", tt.want, tt.got)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/luizbranco/waukeen/web"
 )
@@ -45,8 +46,11 @@ func (h *HTML) Render(w io.Writer, page web.Page) error {
 }
 
 var fns = template.FuncMap{
-	"currency": currency,
-	"contains": contains,
+	"currency":   currency,
+	"decimal":    decimal,
+	"contains":   contains,
+	"hasWeekday": hasWeekday,
+	"percent":    percent,
 }
 
 func (h *HTML) parse(names ...string) (tpl *template.Template, err error) {
@@ -95,6 +99,28 @@ func currency(val int64) string {
 	return res
 }
 
+// decimal formats cents for number inputs, leaving zero blank.
+func decimal(val int64) string {
+	if val == 0 {
+		return ""
+	}
+	sign := ""
+	if val < 0 {
+		sign = "-"
+		val *= -1
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, val/100, val%100)
+}
+
+func hasWeekday(list []time.Weekday, day int) bool {
+	for _, d := range list {
+		if int(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
 func contains(list []string, item string) bool {
 	for _, i := range list {
 		if i == item {
@@ -103,3 +129,8 @@ func contains(list []string, item string) bool {
 	}
 	return false
 }
+
+// percent formats a ratio between 0 and 1 as a whole percentage.
+func percent(ratio float64) string {
+	return fmt.Sprintf("%.0f%%", ratio*100)
+}
@@ -25,3 +25,36 @@ func Test_currency(t *testing.T) {
 		})
 	}
 }
+
+func Test_decimal(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{amount: 0, want: ""},
+		{amount: 50000, want: "500.00"},
+		{amount: 120050, want: "1200.50"},
+		{amount: -5, want: "-0.05"},
+	}
+	for _, tt := range tests {
+		if got := decimal(tt.amount); got != tt.want {
+			t.Errorf("decimal(%d) = %v, want %v", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func Test_percent(t *testing.T) {
+	tests := []struct {
+		ratio float64
+		want  string
+	}{
+		{ratio: 0, want: "0%"},
+		{ratio: 0.8, want: "80%"},
+		{ratio: 0.456, want: "46%"},
+	}
+	for _, tt := range tests {
+		if got := percent(tt.ratio); got != tt.want {
+			t.Errorf("percent(%v) = %v, want %v", tt.ratio, got, tt.want)
+		}
+	}
+}
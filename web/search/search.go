@@ -16,6 +16,8 @@ type Search struct {
 	Accounts []string
 	Types    []string
 	Tags     []string
+	AllTags  bool
+	SubTags  bool
 	Start    string
 	End      string
 }
@@ -28,6 +30,8 @@ func New(r *http.Request) *Search {
 		f.Accounts = r.Form["accounts"]
 		f.Types = r.Form["types"]
 		f.Tags = split(r.FormValue("tags"))
+		f.AllTags = r.FormValue("tags_match") == "all"
+		f.SubTags = r.FormValue("sub_tags") != ""
 		f.Start = r.FormValue("start")
 		f.End = r.FormValue("end")
 
@@ -50,6 +54,8 @@ func New(r *http.Request) *Search {
 	f.Accounts = split(v.Get("accounts"))
 	f.Types = split(v.Get("types"))
 	f.Tags = split(v.Get("tags"))
+	f.AllTags = v.Get("tags_match") == "all"
+	f.SubTags = v.Get("sub_tags") != ""
 	f.Start = v.Get("start")
 	f.End = v.Get("end")
 
@@ -93,11 +99,13 @@ func (s *Search) DBOptions() (o waukeen.TransactionsDBOptions) {
 	}
 
 	if len(o.Types) == 0 {
-		o.Types = []waukeen.TransactionType{waukeen.Debit}
+		o.Types = []waukeen.TransactionType{waukeen.Credit, waukeen.Debit}
 	}
 
 	o.Accounts = s.Accounts
 	o.Tags = s.Tags
+	o.MatchAllTags = s.AllTags
+	o.DescendantTags = s.SubTags
 
 	if today == nil {
 		today = time.Now
@@ -147,6 +155,14 @@ func (f *Search) Save(w http.ResponseWriter) {
 		v.Add("tags", e)
 	}
 
+	if f.AllTags {
+		v.Set("tags_match", "all")
+	}
+
+	if f.SubTags {
+		v.Set("sub_tags", "1")
+	}
+
 	v.Set("start", f.Start)
 	v.Set("end", f.End)
 
@@ -92,6 +92,32 @@ func TestNew(t *testing.T) {
 				End:      "2016-12",
 			},
 		},
+		{
+			name: "all tags form values",
+			args: args{
+				v: url.Values{
+					"tags":       []string{"food, gift"},
+					"tags_match": []string{"all"},
+				},
+			},
+			want: &Search{
+				Tags:    []string{"food", "gift"},
+				AllTags: true,
+			},
+		},
+		{
+			name: "sub tags form values",
+			args: args{
+				v: url.Values{
+					"tags":     []string{"food"},
+					"sub_tags": []string{"1"},
+				},
+			},
+			want: &Search{
+				Tags:    []string{"food"},
+				SubTags: true,
+			},
+		},
 		{
 			name: "partial cookie values",
 			args: args{
@@ -157,7 +183,7 @@ func TestSearch_DBOptions(t *testing.T) {
 			name:   "no fields filled",
 			fields: fields{},
 			want: waukeen.TransactionsDBOptions{
-				Types: []waukeen.TransactionType{waukeen.Debit},
+				Types: []waukeen.TransactionType{waukeen.Credit, waukeen.Debit},
 				Start: time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
 				End:   time.Date(2016, 10, 31, 0, 0, 0, 0, time.UTC),
 			},
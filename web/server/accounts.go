@@ -2,6 +2,7 @@ package server
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/luizbranco/waukeen"
 	"github.com/luizbranco/waukeen/web"
@@ -35,6 +36,13 @@ func (srv *Server) accounts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	budgeted, err := srv.rolloverHistory(opt, tags)
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+	budgeted = append(budgeted, transactions...)
+
 	var total int64
 	for _, t := range transactions {
 		total += t.Amount
@@ -50,7 +58,15 @@ func (srv *Server) accounts(w http.ResponseWriter, r *http.Request) {
 	opt.Accounts = ids
 
 	months := monthSpam(opt)
-	budgets := srv.BudgetCalculator.Calculate(months, transactions, tags)
+	budgets := srv.BudgetCalculator.Calculate(opt.Start, months, budgeted, tags)
+	totals := srv.BudgetCalculator.Totals(opt.Start, months, transactions, tags)
+
+	var overall waukeen.BudgetTotals
+	for _, t := range totals {
+		overall.Expected += t.Expected
+		overall.Income += t.Income
+		overall.Expenses += t.Expenses
+	}
 
 	content := struct {
 		Form         *search.Search
@@ -58,12 +74,16 @@ func (srv *Server) accounts(w http.ResponseWriter, r *http.Request) {
 		Transactions []waukeen.Transaction
 		Total        int64
 		Budgets      []waukeen.Budget
+		Totals       []waukeen.BudgetTotals
+		Overall      waukeen.BudgetTotals
 	}{
 		Form:         form,
 		Accounts:     accs,
 		Transactions: transactions,
 		Total:        total,
 		Budgets:      budgets,
+		Totals:       totals,
+		Overall:      overall,
 	}
 
 	form.Save(w)
@@ -78,6 +98,39 @@ func (srv *Server) accounts(w http.ResponseWriter, r *http.Request) {
 	srv.render(w, page)
 }
 
+// rolloverHistory finds the transactions before the months shown, which
+// rollover tags need to know what they carry into the first one. There is
+// none to find without a rollover tag.
+func (srv *Server) rolloverHistory(opt waukeen.TransactionsDBOptions,
+	tags []waukeen.Tag) ([]waukeen.Transaction, error) {
+
+	rollover := false
+	for _, t := range tags {
+		rollover = rollover || t.Rollover
+	}
+	if !rollover {
+		return nil, nil
+	}
+
+	start := opt.Start
+	opt.Start = time.Time{}
+	opt.End = start.AddDate(0, 0, -1)
+
+	trs, err := srv.DB.FindTransactions(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// the end date matches up to the day after it
+	var history []waukeen.Transaction
+	for _, t := range trs {
+		if t.Date.Before(start) {
+			history = append(history, t)
+		}
+	}
+	return history, nil
+}
+
 func monthSpam(opt waukeen.TransactionsDBOptions) int {
 	years := opt.End.Year() - opt.Start.Year()
 	months := (int(opt.End.Month()) + (years * 12)) - int(opt.Start.Month())
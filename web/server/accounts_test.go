@@ -27,10 +27,14 @@ func TestAccounts(t *testing.T) {
 		return nil, nil
 	}
 
-	budgeter.CalculateMethod = func(months int, trs []waukeen.Transaction,
+	budgeter.CalculateMethod = func(start time.Time, months int, trs []waukeen.Transaction,
 		tags []waukeen.Tag) []waukeen.Budget {
 		return nil
 	}
+	budgeter.TotalsMethod = func(start time.Time, months int, trs []waukeen.Transaction,
+		tags []waukeen.Tag) []waukeen.BudgetTotals {
+		return nil
+	}
 
 	srv := &Server{DB: db, BudgetCalculator: budgeter}
 
@@ -162,6 +166,64 @@ func TestAccounts(t *testing.T) {
 			t.Errorf("wants %d status code, got %d", code, res.Code)
 		}
 	})
+
+	t.Run("Rollover History", func(t *testing.T) {
+		oct := time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC)
+		window := []waukeen.Transaction{{ID: "3", Date: oct, Amount: -1000}}
+		history := []waukeen.Transaction{
+			{ID: "1", Date: oct.AddDate(0, -2, 0), Amount: -2000},
+			{ID: "2", Date: oct.AddDate(0, 0, -1), Amount: -3000},
+			{ID: "3", Date: oct, Amount: -1000},
+		}
+
+		db.FindAccountsMethod = func(ids ...string) ([]waukeen.Account, error) {
+			return []waukeen.Account{{ID: "2"}}, nil
+		}
+		db.FindTransactionsMethod = func(opts waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			if opts.Start.IsZero() {
+				end := oct.AddDate(0, 0, -1)
+				if !opts.End.Equal(end) {
+					t.Errorf("wants history to end on %s, got %s", end, opts.End)
+				}
+				return history, nil
+			}
+			return window, nil
+		}
+		db.AllTagsMethod = func() ([]waukeen.Tag, error) {
+			return []waukeen.Tag{{Name: "groceries", Rollover: true}}, nil
+		}
+
+		var calculated, totaled []waukeen.Transaction
+		budgeter.CalculateMethod = func(start time.Time, months int, trs []waukeen.Transaction,
+			tags []waukeen.Tag) []waukeen.Budget {
+			calculated = trs
+			return nil
+		}
+		budgeter.TotalsMethod = func(start time.Time, months int, trs []waukeen.Transaction,
+			tags []waukeen.Tag) []waukeen.BudgetTotals {
+			totaled = trs
+			return nil
+		}
+
+		req := httptest.NewRequest("GET", "/accounts/", nil)
+		req.Form = url.Values{}
+		req.Form.Set("start", "2016-10")
+		req.Form.Set("end", "2016-10")
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		want := append(history[:2:2], window...)
+		if !reflect.DeepEqual(want, calculated) {
+			t.Errorf("wants budget of %+v, got %+v", want, calculated)
+		}
+		if !reflect.DeepEqual(window, totaled) {
+			t.Errorf("wants totals of %+v, got %+v", window, totaled)
+		}
+	})
 }
 
 func TestMonthSpam(t *testing.T) {
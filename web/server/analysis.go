@@ -0,0 +1,267 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/luizbranco/waukeen"
+)
+
+// defaultAnalysisMonths is how far back rules are checked against
+// transactions unless the rules page asks otherwise.
+const defaultAnalysisMonths = 6
+
+// ruleAnalysis is the report shown on the rules page.
+type ruleAnalysis struct {
+	Months     int
+	Dead       []waukeen.Rule
+	Shadowed   []ruleFinding
+	Collisions []ruleFinding
+	Tags       []ruleFinding
+}
+
+type ruleFinding struct {
+	Rule   waukeen.Rule
+	Reason string
+}
+
+func (a ruleAnalysis) Empty() bool {
+	return len(a.Dead) == 0 && len(a.Shadowed) == 0 && len(a.Collisions) == 0 &&
+		len(a.Tags) == 0
+}
+
+// analyzeRules finds rules that matched none of the transactions, rules
+// shadowed by an earlier one, replace rules fighting over the alias and tag
+// rules pointing at unknown or misspelled tags. Rules must be in priority
+// order.
+func analyzeRules(rules []waukeen.Rule, trs []waukeen.Transaction,
+	tags []waukeen.Tag, transformer waukeen.TransactionTransformer) ruleAnalysis {
+
+	var a ruleAnalysis
+
+	compiled := waukeen.CompileRules(rules)
+	matches := make([]map[string]bool, len(rules))
+	for i, r := range compiled {
+		matches[i] = make(map[string]bool)
+		for _, t := range trs {
+			c := t
+			c.Tags = append([]string(nil), t.Tags...)
+			if transformer.Transform(&c, r) {
+				matches[i][t.ID] = true
+			}
+		}
+		if len(matches[i]) == 0 {
+			a.Dead = append(a.Dead, rules[i])
+		}
+	}
+
+	for i, r := range rules {
+		if reason := shadowedBy(rules, matches, i); reason != "" {
+			a.Shadowed = append(a.Shadowed, ruleFinding{r, reason})
+		}
+		if reason := collidesWith(rules, matches, i); reason != "" {
+			a.Collisions = append(a.Collisions, ruleFinding{r, reason})
+		}
+		if reason := unknownTag(rules, tags, r); reason != "" {
+			a.Tags = append(a.Tags, ruleFinding{r, reason})
+		}
+	}
+
+	return a
+}
+
+// shadowedBy explains why an earlier rule makes rules[i] useless: it stops
+// on every transaction rules[i] matches, it already does the same to them,
+// or its match is the singular of a plural doing the same.
+func shadowedBy(rules []waukeen.Rule, matches []map[string]bool, i int) string {
+	r := rules[i]
+
+	for j := 0; j < i; j++ {
+		prev := rules[j]
+		same := prev.Type == r.Type && prev.Result == r.Result
+
+		if len(matches[i]) > 0 && covers(matches[j], matches[i]) {
+			switch {
+			case prev.Stop:
+				return fmt.Sprintf("rule %s stops first on all %d of its matches",
+					ruleName(prev), len(matches[i]))
+			case same:
+				return fmt.Sprintf("rule %s already does the same to all %d of its matches",
+					ruleName(prev), len(matches[i]))
+			}
+		}
+
+		if same && prev.Field == r.Field && prev.Mode == r.Mode &&
+			prev.Mode != waukeen.RegexMatch && isPlural(prev.Match, r.Match) {
+			return fmt.Sprintf("plural of rule %s, a single regex rule can match both",
+				ruleName(prev))
+		}
+	}
+
+	return ""
+}
+
+// collidesWith lists replace rules giving another alias to transactions
+// rules[i] matches, or spelling the same alias differently.
+func collidesWith(rules []waukeen.Rule, matches []map[string]bool, i int) string {
+	r := rules[i]
+	if r.Type != waukeen.ReplaceRule {
+		return ""
+	}
+
+	var reasons []string
+
+	for j, other := range rules {
+		if j == i || other.Type != waukeen.ReplaceRule || other.Result == r.Result {
+			continue
+		}
+
+		if normalize(other.Result) == normalize(r.Result) {
+			reasons = append(reasons, fmt.Sprintf("rule %s spells the alias %q",
+				ruleName(other), other.Result))
+			continue
+		}
+
+		n := 0
+		for id := range matches[i] {
+			if matches[j][id] {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+
+		winner := "overrides it"
+		if j < i {
+			winner = "is overridden by it"
+		}
+		reasons = append(reasons, fmt.Sprintf("rule %s aliases %d of the same transactions %q and %s",
+			ruleName(other), n, other.Result, winner))
+	}
+
+	return strings.Join(reasons, "; ")
+}
+
+// unknownTag flags tag rules whose tag was never created, or that looks like
+// a misspelling of a tag with a budget or used by more rules.
+func unknownTag(rules []waukeen.Rule, tags []waukeen.Tag, r waukeen.Rule) string {
+	if r.Type != waukeen.TagRule {
+		return ""
+	}
+
+	uses := make(map[string]int)
+	for _, rule := range rules {
+		if rule.Type == waukeen.TagRule {
+			uses[rule.Result]++
+		}
+	}
+
+	weight := func(name string) int {
+		w := uses[name]
+		for _, t := range tags {
+			if t.Name == name && (t.MonthlyBudget != 0 || len(t.Budgets) > 0) {
+				w += len(rules) + 1
+			}
+		}
+		return w
+	}
+
+	known := false
+	closest := ""
+	for _, t := range tags {
+		if t.Name == r.Result {
+			known = true
+			continue
+		}
+		if similar(t.Name, r.Result) && weight(t.Name) > weight(r.Result) {
+			closest = t.Name
+		}
+	}
+
+	switch {
+	case closest != "":
+		return fmt.Sprintf("tag %q looks like a misspelling of %q", r.Result, closest)
+	case !known:
+		return fmt.Sprintf("tag %q doesn't exist", r.Result)
+	}
+	return ""
+}
+
+func ruleName(r waukeen.Rule) string {
+	return fmt.Sprintf("#%d %q", r.Priority, r.Match)
+}
+
+// covers reports whether every id in b is also in a.
+func covers(a, b map[string]bool) bool {
+	for id := range b {
+		if !a[id] {
+			return false
+		}
+	}
+	return true
+}
+
+func isPlural(singular, plural string) bool {
+	s, p := strings.ToLower(singular), strings.ToLower(plural)
+	return p == s+"s" || p == s+"es"
+}
+
+// normalize keeps only the lower case letters and digits of an alias.
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// similar tags are at most two edits apart, one for short names.
+func similar(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	if a == b {
+		return false
+	}
+	max := 2
+	if len(a) < 6 || len(b) < 6 {
+		max = 1
+	}
+	return distance(a, b) <= max
+}
+
+// distance is the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(vals ...int) int {
+	m := vals[0]
+	for _, v := range vals[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/luizbranco/waukeen"
+)
+
+func TestAnalyzeRules(t *testing.T) {
+	rules := []waukeen.Rule{
+		{Priority: 1, Type: waukeen.TagRule, Match: "PIZZA", Result: "restaurants"},
+		{Priority: 2, Type: waukeen.TagRule, Match: "PIZZA", Result: "pizza"},
+		{Priority: 3, Type: waukeen.TagRule, Match: "BURGER", Result: "restaurants"},
+		{Priority: 4, Type: waukeen.TagRule, Match: "BURGERS", Result: "restaurants"},
+		{Priority: 5, Type: waukeen.ReplaceRule, Match: "DOMINOS", Result: "Dominos", Stop: true},
+		{Priority: 6, Type: waukeen.ReplaceRule, Match: "DOMINOS PIZZA", Result: "Domino's"},
+		{Priority: 7, Type: waukeen.TagRule, Match: "TACO", Result: "restaurants"},
+		{Priority: 8, Type: waukeen.TagRule, Match: "TACOS", Result: "restaurants"},
+		{Priority: 9, Type: waukeen.TagRule, Match: "BELL", Result: "utitilies"},
+		{Priority: 10, Type: waukeen.ReplaceRule, Match: "UBER", Result: "Uber"},
+		{Priority: 11, Type: waukeen.ReplaceRule, Match: "EATS", Result: "Food delivery"},
+		{Priority: 12, Type: waukeen.TagRule, Match: "GYM", Result: "fitnes"},
+	}
+
+	trs := []waukeen.Transaction{
+		{ID: "1", Title: "DOMINOS PIZZA"},
+		{ID: "2", Title: "PIZZA PIZZA"},
+		{ID: "3", Title: "BURGERS"},
+		{ID: "4", Title: "BURGER KING"},
+		{ID: "5", Title: "BELL MOBILE"},
+		{ID: "6", Title: "UBER EATS"},
+	}
+
+	tags := []waukeen.Tag{
+		{Name: "restaurants"},
+		{Name: "pizza"},
+		{Name: "utilities", MonthlyBudget: 10000},
+		{Name: "utitilies"},
+	}
+
+	a := analyzeRules(rules, trs, tags, rerunTransformer())
+
+	findings := func(list []ruleFinding) map[string]string {
+		m := make(map[string]string)
+		for _, f := range list {
+			m[f.Rule.Match] = f.Reason
+		}
+		return m
+	}
+
+	t.Run("Dead", func(t *testing.T) {
+		var got []string
+		for _, r := range a.Dead {
+			got = append(got, r.Match)
+		}
+		want := []string{"TACO", "TACOS", "GYM"}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("wants %v, got %v", want, got)
+		}
+	})
+
+	t.Run("Shadowed", func(t *testing.T) {
+		want := map[string]string{
+			"BURGERS":       `rule #3 "BURGER" already does the same to all 1 of its matches`,
+			"DOMINOS PIZZA": `rule #5 "DOMINOS" stops first on all 1 of its matches`,
+			"TACOS":         `plural of rule #7 "TACO", a single regex rule can match both`,
+		}
+		got := findings(a.Shadowed)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("wants\n%v\ngot\n%v", want, got)
+		}
+	})
+
+	t.Run("Collisions", func(t *testing.T) {
+		want := map[string]string{
+			"DOMINOS":       `rule #6 "DOMINOS PIZZA" spells the alias "Domino's"`,
+			"DOMINOS PIZZA": `rule #5 "DOMINOS" spells the alias "Dominos"`,
+			"UBER":          `rule #11 "EATS" aliases 1 of the same transactions "Food delivery" and overrides it`,
+			"EATS":          `rule #10 "UBER" aliases 1 of the same transactions "Uber" and is overridden by it`,
+		}
+		got := findings(a.Collisions)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("wants\n%v\ngot\n%v", want, got)
+		}
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		want := map[string]string{
+			"BELL": `tag "utitilies" looks like a misspelling of "utilities"`,
+			"GYM":  `tag "fitnes" doesn't exist`,
+		}
+		got := findings(a.Tags)
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("wants\n%v\ngot\n%v", want, got)
+		}
+	})
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"taco", "tacos", 1},
+		{"utilities", "utitilies", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) wants %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,514 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/pkg/errors"
+)
+
+const apiPrefix = "/api/v1/"
+
+type apiError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (srv *Server) apiMux(mux *http.ServeMux) {
+	mux.HandleFunc(apiPrefix+"accounts", srv.apiAccounts)
+	mux.HandleFunc(apiPrefix+"accounts/", srv.apiAccounts)
+	mux.HandleFunc(apiPrefix+"transactions", srv.apiTransactions)
+	mux.HandleFunc(apiPrefix+"transactions/", srv.apiTransactions)
+	mux.HandleFunc(apiPrefix+"tags", srv.apiTags)
+	mux.HandleFunc(apiPrefix+"tags/", srv.apiTags)
+	mux.HandleFunc(apiPrefix+"rules", srv.apiRules)
+	mux.HandleFunc(apiPrefix+"rules/", srv.apiRules)
+	mux.HandleFunc(apiPrefix+"statements", srv.apiStatements)
+	mux.HandleFunc(apiPrefix, func(w http.ResponseWriter, r *http.Request) {
+		renderJSONError(w, http.StatusNotFound, errors.New("not found"))
+	})
+}
+
+func renderJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if v != nil {
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+func renderJSONError(w http.ResponseWriter, status int, err error) {
+	body := struct {
+		Error apiError `json:"error"`
+	}{apiError{Status: status, Message: err.Error()}}
+	renderJSON(w, status, body)
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	err := fmt.Errorf("method %s not allowed", r.Method)
+	renderJSONError(w, http.StatusMethodNotAllowed, err)
+}
+
+// apiID returns the resource id following the collection name in the path,
+// empty when the request is for the whole collection.
+func apiID(r *http.Request, collection string) string {
+	id := strings.TrimPrefix(r.URL.Path, apiPrefix+collection)
+	return strings.Trim(id, "/")
+}
+
+func decodeJSON(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	return errors.Wrap(err, "invalid json body")
+}
+
+func (srv *Server) apiAccounts(w http.ResponseWriter, r *http.Request) {
+	id := apiID(r, "accounts")
+
+	var acc *waukeen.Account
+	if id != "" {
+		accs, err := srv.DB.FindAccounts(id)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if len(accs) == 0 {
+			renderJSONError(w, http.StatusNotFound, errors.New("account not found"))
+			return
+		}
+		acc = &accs[0]
+	}
+
+	switch {
+	case r.Method == "GET" && acc == nil:
+		accs, err := srv.DB.FindAccounts()
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if accs == nil {
+			accs = []waukeen.Account{}
+		}
+		renderJSON(w, http.StatusOK, accs)
+	case r.Method == "GET":
+		renderJSON(w, http.StatusOK, acc)
+	case r.Method == "POST" && acc == nil:
+		acc = &waukeen.Account{}
+		err := decodeJSON(r, acc)
+		if err == nil && acc.Number == "" {
+			err = errors.New("account number is required")
+		}
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		acc.ID = ""
+		err = srv.DB.CreateAccount(acc)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusCreated, acc)
+	case r.Method == "PUT" && acc != nil:
+		err := decodeJSON(r, acc)
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		acc.ID = id
+		err = srv.DB.UpdateAccount(acc)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusOK, acc)
+	case r.Method == "DELETE" && acc != nil:
+		err := srv.DB.DeleteAccount(id)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusNoContent, nil)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func (srv *Server) apiTransactions(w http.ResponseWriter, r *http.Request) {
+	id := apiID(r, "transactions")
+
+	var tr *waukeen.Transaction
+	if id != "" {
+		var err error
+		tr, err = srv.DB.FindTransaction(id)
+		if errors.Cause(err) == sql.ErrNoRows {
+			renderJSONError(w, http.StatusNotFound, errors.New("transaction not found"))
+			return
+		}
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	switch {
+	case r.Method == "GET" && tr == nil:
+		opts, err := transactionsOptions(r)
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		trs, err := srv.DB.FindTransactions(opts)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if trs == nil {
+			trs = []waukeen.Transaction{}
+		}
+		renderJSON(w, http.StatusOK, trs)
+	case r.Method == "GET":
+		renderJSON(w, http.StatusOK, tr)
+	case r.Method == "POST" && tr == nil:
+		tr = &waukeen.Transaction{}
+		err := decodeJSON(r, tr)
+		if err == nil {
+			err = validTransaction(tr)
+		}
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		tr.ID = ""
+		tr.ImportID = ""
+		err = srv.DB.CreateTransaction(tr)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusCreated, tr)
+	case r.Method == "PUT" && tr != nil:
+		alias, tags := tr.Alias, append([]string(nil), tr.Tags...)
+		err := decodeJSON(r, tr)
+		if err == nil {
+			err = validTransaction(tr)
+		}
+		if tr.Alias != alias {
+			tr.AliasEdited = true
+		}
+		if !sameTags(tr.Tags, tags) {
+			tr.TagsEdited = true
+		}
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		tr.ID = id
+		err = srv.DB.UpdateTransaction(tr)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusOK, tr)
+	case r.Method == "DELETE" && tr != nil:
+		err := srv.DB.DeleteTransaction(id)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusNoContent, nil)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func validTransaction(t *waukeen.Transaction) error {
+	switch {
+	case t.AccountID == "":
+		return errors.New("transaction account_id is required")
+	case t.FITID == "":
+		return errors.New("transaction fitid is required")
+	case t.Title == "":
+		return errors.New("transaction title is required")
+	}
+	return nil
+}
+
+// transactionsOptions reads the same filters as the accounts search form,
+// accounts and types can be repeated or comma separated and dates are either
+// months (2006-01) or days (2006-01-02).
+func transactionsOptions(r *http.Request) (waukeen.TransactionsDBOptions, error) {
+	var opts waukeen.TransactionsDBOptions
+
+	q := r.URL.Query()
+
+	values := func(key string) []string {
+		var list []string
+		for _, v := range q[key] {
+			list = append(list, splitTags(v)...)
+		}
+		return list
+	}
+
+	opts.Accounts = values("accounts")
+	opts.FITIDs = values("fitids")
+	opts.Tags = values("tags")
+	opts.MatchAllTags = q.Get("tags_match") == "all"
+	opts.DescendantTags = q.Get("sub_tags") != ""
+
+	for _, t := range values("types") {
+		n, err := strconv.Atoi(t)
+		if err != nil {
+			return opts, errors.Errorf("invalid transaction type %s", t)
+		}
+		opts.Types = append(opts.Types, waukeen.TransactionType(n))
+	}
+
+	var err error
+
+	opts.Start, err = parseDate(q.Get("start"), false)
+	if err != nil {
+		return opts, errors.Wrap(err, "invalid start date")
+	}
+
+	opts.End, err = parseDate(q.Get("end"), true)
+	if err != nil {
+		return opts, errors.Wrap(err, "invalid end date")
+	}
+
+	return opts, nil
+}
+
+// parseDate accepts a day or a month, a month is its first day or its last
+// when end is set.
+func parseDate(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	d, err := time.Parse("2006-01-02", s)
+	if err == nil {
+		return d, nil
+	}
+
+	d, err = time.Parse("2006-01", s)
+	if err != nil {
+		return d, err
+	}
+
+	if end {
+		d = d.AddDate(0, 1, -1)
+	}
+
+	return d, nil
+}
+
+func (srv *Server) apiTags(w http.ResponseWriter, r *http.Request) {
+	name := apiID(r, "tags")
+
+	var tag *waukeen.Tag
+	if name != "" {
+		var err error
+		tag, err = srv.DB.FindTag(name)
+		if err != nil {
+			renderJSONError(w, http.StatusNotFound, errors.New("tag not found"))
+			return
+		}
+	}
+
+	switch {
+	case r.Method == "GET" && tag == nil:
+		tags, err := srv.DB.AllTags()
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if tags == nil {
+			tags = []waukeen.Tag{}
+		}
+		renderJSON(w, http.StatusOK, tags)
+	case r.Method == "GET":
+		renderJSON(w, http.StatusOK, tag)
+	case r.Method == "POST" && tag == nil:
+		tag = &waukeen.Tag{}
+		err := decodeJSON(r, tag)
+		if err == nil && tag.Name == "" {
+			err = errors.New("tag name is required")
+		}
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		tag.ID = ""
+		err = srv.DB.CreateTag(tag)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusCreated, tag)
+	case r.Method == "PUT" && tag != nil:
+		id := tag.ID
+		err := decodeJSON(r, tag)
+		if err == nil && tag.Name == "" {
+			err = errors.New("tag name is required")
+		}
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		tag.ID = id
+		err = srv.DB.UpdateTag(tag)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusOK, tag)
+	case r.Method == "DELETE" && tag != nil:
+		err := srv.DB.DeleteTag(tag.ID)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusNoContent, nil)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func (srv *Server) apiRules(w http.ResponseWriter, r *http.Request) {
+	id := apiID(r, "rules")
+
+	var rule *waukeen.Rule
+	if id != "" {
+		rules, err := srv.DB.FindRules(id)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if len(rules) == 0 {
+			renderJSONError(w, http.StatusNotFound, errors.New("rule not found"))
+			return
+		}
+		rule = &rules[0]
+	}
+
+	switch {
+	case r.Method == "GET" && rule == nil:
+		rules, err := srv.DB.FindRules()
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if rules == nil {
+			rules = []waukeen.Rule{}
+		}
+		renderJSON(w, http.StatusOK, rules)
+	case r.Method == "GET":
+		renderJSON(w, http.StatusOK, rule)
+	case r.Method == "POST" && rule == nil:
+		rule = &waukeen.Rule{}
+		err := decodeJSON(r, rule)
+		if err == nil {
+			err = rule.Validate()
+		}
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		rule.ID = ""
+		err = srv.DB.CreateRule(rule)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusCreated, rule)
+	case r.Method == "PUT" && rule != nil:
+		priority := rule.Priority
+		err := decodeJSON(r, rule)
+		if err == nil {
+			err = rule.Validate()
+		}
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		rule.ID = id
+		rule.Priority = priority
+		err = srv.DB.UpdateRule(rule)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusOK, rule)
+	case r.Method == "DELETE" && rule != nil:
+		err := srv.DB.DeleteRule(id)
+		if err != nil {
+			renderJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		renderJSON(w, http.StatusNoContent, nil)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// apiStatements imports the uploaded statement files straight away, applying
+// every rule, and returns the resulting imports.
+func (srv *Server) apiStatements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	err := r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		renderJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	files := r.MultipartForm.File["statement"]
+	if len(files) == 0 {
+		renderJSONError(w, http.StatusBadRequest, errors.New("no statement file uploaded"))
+		return
+	}
+
+	format := r.FormValue("format")
+	account := r.FormValue("account")
+
+	var list []waukeen.Statement
+
+	for _, fh := range files {
+		stmts, err := srv.importStatement(fh, format, account)
+		if err != nil {
+			renderJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		list = append(list, stmts...)
+	}
+
+	imports := []waukeen.Import{}
+
+	err = srv.DB.WithTx(func(db waukeen.Database) error {
+		for _, stmt := range list {
+			err := db.CreateStatement(stmt, srv.Transformer)
+			if err != nil {
+				return err
+			}
+			imports = append(imports, *stmt.Import)
+		}
+		return nil
+	})
+
+	if err != nil {
+		renderJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	srv.retrainClassifier()
+	renderJSON(w, http.StatusCreated, imports)
+}
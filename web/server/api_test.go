@@ -0,0 +1,558 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/mock"
+)
+
+func apiErrorBody(t *testing.T, res *httptest.ResponseRecorder) apiError {
+	var body struct {
+		Error apiError `json:"error"`
+	}
+	err := json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Errorf("wants json error body, got %s", err)
+	}
+	return body.Error
+}
+
+func TestAPIAccounts(t *testing.T) {
+	t.Run("List accounts", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(ids ...string) ([]waukeen.Account, error) {
+			return []waukeen.Account{{ID: "1", Number: "123"}}, nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/api/v1/accounts", nil)
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		ct := "application/json; charset=utf-8"
+		if got := res.Header().Get("Content-Type"); got != ct {
+			t.Errorf("wants %s content type, got %s", ct, got)
+		}
+
+		want := `[{"id":"1","number":"123","name":"","type":0,"currency":"","balance":0}]`
+		if got := strings.TrimSpace(res.Body.String()); got != want {
+			t.Errorf("wants %s, got %s", want, got)
+		}
+	})
+
+	t.Run("Empty list", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(ids ...string) ([]waukeen.Account, error) {
+			return nil, nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/api/v1/accounts/", nil)
+		res := serverTest(srv, req)
+
+		want := `[]`
+		if got := strings.TrimSpace(res.Body.String()); got != want {
+			t.Errorf("wants %s, got %s", want, got)
+		}
+	})
+
+	t.Run("Account not found", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(ids ...string) ([]waukeen.Account, error) {
+			return nil, nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/api/v1/accounts/99", nil)
+		res := serverTest(srv, req)
+
+		code := 404
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		e := apiErrorBody(t, res)
+		if e.Status != code || e.Message != "account not found" {
+			t.Errorf("wants account not found error, got %+v", e)
+		}
+	})
+
+	t.Run("Create invalid account", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v1/accounts", strings.NewReader(`{"name": "Checking"}`))
+		res := serverTest(&Server{DB: &mock.Database{}}, req)
+
+		code := 400
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Create account db error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.CreateAccountMethod = func(*waukeen.Account) error {
+			return errors.New("not implemented")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/api/v1/accounts", strings.NewReader(`{"number": "123"}`))
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		e := apiErrorBody(t, res)
+		if e.Message != "not implemented" {
+			t.Errorf("wants not implemented error, got %+v", e)
+		}
+	})
+
+	t.Run("Create account", func(t *testing.T) {
+		db := &mock.Database{}
+		db.CreateAccountMethod = func(a *waukeen.Account) error {
+			want := &waukeen.Account{Number: "123", Name: "Checking", Type: waukeen.Checking}
+			if !reflect.DeepEqual(want, a) {
+				t.Errorf("wants %+v, got %+v", want, a)
+			}
+			a.ID = "1"
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		body := `{"id": "7", "number": "123", "name": "Checking", "type": 1}`
+		req := httptest.NewRequest("POST", "/api/v1/accounts", strings.NewReader(body))
+		res := serverTest(srv, req)
+
+		code := 201
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		var got waukeen.Account
+		json.NewDecoder(res.Body).Decode(&got)
+		if got.ID != "1" {
+			t.Errorf("wants account id 1, got %s", got.ID)
+		}
+	})
+
+	t.Run("Update account", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(ids ...string) ([]waukeen.Account, error) {
+			return []waukeen.Account{{ID: "1", Number: "123", Name: "Checking"}}, nil
+		}
+		db.UpdateAccountMethod = func(a *waukeen.Account) error {
+			want := &waukeen.Account{ID: "1", Number: "123", Name: "Savings"}
+			if !reflect.DeepEqual(want, a) {
+				t.Errorf("wants %+v, got %+v", want, a)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("PUT", "/api/v1/accounts/1", strings.NewReader(`{"name": "Savings"}`))
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Delete account", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(ids ...string) ([]waukeen.Account, error) {
+			return []waukeen.Account{{ID: "1"}}, nil
+		}
+		db.DeleteAccountMethod = func(id string) error {
+			if id != "1" {
+				t.Errorf("wants account id 1, got %s", id)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("DELETE", "/api/v1/accounts/1", nil)
+		res := serverTest(srv, req)
+
+		code := 204
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/v1/accounts", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
+
+func TestAPITransactions(t *testing.T) {
+	t.Run("List with filters", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionsMethod = func(opts waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			want := waukeen.TransactionsDBOptions{
+				Accounts:       []string{"1", "2"},
+				Types:          []waukeen.TransactionType{waukeen.Debit},
+				Tags:           []string{"food", "gift"},
+				MatchAllTags:   true,
+				DescendantTags: true,
+				Start:          time.Date(2016, 10, 1, 0, 0, 0, 0, time.UTC),
+				End:            time.Date(2016, 11, 30, 0, 0, 0, 0, time.UTC),
+			}
+			if !reflect.DeepEqual(want, opts) {
+				t.Errorf("wants %+v, got %+v", want, opts)
+			}
+			return nil, nil
+		}
+		srv := &Server{DB: db}
+
+		uri := "/api/v1/transactions?accounts=1,2&types=2&tags=food,gift&tags_match=all&sub_tags=1&start=2016-10&end=2016-11"
+		req := httptest.NewRequest("GET", uri, nil)
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Invalid filters", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/transactions?start=october", nil)
+		res := serverTest(&Server{DB: &mock.Database{}}, req)
+
+		code := 400
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Transaction not found", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionMethod = func(string) (*waukeen.Transaction, error) {
+			return nil, sql.ErrNoRows
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/api/v1/transactions/99", nil)
+		res := serverTest(srv, req)
+
+		code := 404
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Find transaction DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionMethod = func(string) (*waukeen.Transaction, error) {
+			return nil, errors.New("database is locked")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/api/v1/transactions/99", nil)
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Create invalid transaction", func(t *testing.T) {
+		body := `{"account_id": "1", "title": "Dominos"}`
+		req := httptest.NewRequest("POST", "/api/v1/transactions", strings.NewReader(body))
+		res := serverTest(&Server{DB: &mock.Database{}}, req)
+
+		code := 400
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		e := apiErrorBody(t, res)
+		if e.Message != "transaction fitid is required" {
+			t.Errorf("wants fitid error, got %+v", e)
+		}
+	})
+
+	t.Run("Update transaction", func(t *testing.T) {
+		tr := &waukeen.Transaction{ID: "1", AccountID: "1", FITID: "01", Title: "DOMINOS"}
+
+		db := &mock.Database{}
+		db.FindTransactionMethod = func(string) (*waukeen.Transaction, error) {
+			return tr, nil
+		}
+		db.UpdateTransactionMethod = func(got *waukeen.Transaction) error {
+			want := &waukeen.Transaction{ID: "1", AccountID: "1", FITID: "01",
+				Title: "DOMINOS", Alias: "Dominos", Tags: []string{"pizza"},
+				AliasEdited: true, TagsEdited: true}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("wants %+v, got %+v", want, got)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		body := `{"alias": "Dominos", "tags": ["pizza"]}`
+		req := httptest.NewRequest("PUT", "/api/v1/transactions/1", strings.NewReader(body))
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
+
+func TestAPITags(t *testing.T) {
+	t.Run("Update tag keeps id", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTagMethod = func(name string) (*waukeen.Tag, error) {
+			return &waukeen.Tag{ID: "3", Name: name}, nil
+		}
+		db.UpdateTagMethod = func(got *waukeen.Tag) error {
+			want := &waukeen.Tag{ID: "3", Name: "food", MonthlyBudget: 500}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("wants %+v, got %+v", want, got)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		body := `{"id": "9", "monthly_budget": 500}`
+		req := httptest.NewRequest("PUT", "/api/v1/tags/food", strings.NewReader(body))
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Delete tag", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTagMethod = func(name string) (*waukeen.Tag, error) {
+			return &waukeen.Tag{ID: "3", Name: name}, nil
+		}
+		db.DeleteTagMethod = func(id string) error {
+			if id != "3" {
+				t.Errorf("wants tag id 3, got %s", id)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("DELETE", "/api/v1/tags/food", nil)
+		res := serverTest(srv, req)
+
+		code := 204
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
+
+func TestAPIRules(t *testing.T) {
+	t.Run("Create invalid rule type", func(t *testing.T) {
+		body := `{"type": "regex", "match": "dominos", "result": "pizza"}`
+		req := httptest.NewRequest("POST", "/api/v1/rules", strings.NewReader(body))
+		res := serverTest(&Server{DB: &mock.Database{}}, req)
+
+		code := 400
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Create rule", func(t *testing.T) {
+		db := &mock.Database{}
+		db.CreateRuleMethod = func(r *waukeen.Rule) error {
+			want := &waukeen.Rule{Type: waukeen.TagRule, Match: "dominos", Result: "pizza"}
+			if !reflect.DeepEqual(want, r) {
+				t.Errorf("wants %+v, got %+v", want, r)
+			}
+			r.ID = "1"
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		body := `{"type": "tag", "match": "dominos", "result": "pizza"}`
+		req := httptest.NewRequest("POST", "/api/v1/rules", strings.NewReader(body))
+		res := serverTest(srv, req)
+
+		code := 201
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		want := `{"id":"1","type":"tag","match":"dominos","result":"pizza"}`
+		if got := strings.TrimSpace(res.Body.String()); got != want {
+			t.Errorf("wants %s, got %s", want, got)
+		}
+	})
+
+	t.Run("Update rule", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindRulesMethod = func(ids ...string) ([]waukeen.Rule, error) {
+			return []waukeen.Rule{{ID: "3", Type: waukeen.TagRule, Match: "dominos",
+				Result: "pizza", Priority: 2}}, nil
+		}
+		db.UpdateRuleMethod = func(r *waukeen.Rule) error {
+			want := &waukeen.Rule{ID: "3", Type: waukeen.ReplaceRule, Match: "dominos",
+				Result: "Dominos", Priority: 2}
+			if !reflect.DeepEqual(want, r) {
+				t.Errorf("wants %+v, got %+v", want, r)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		body := `{"id": "9", "type": "replace", "match": "dominos", "result": "Dominos", "priority": 7}`
+		req := httptest.NewRequest("PUT", "/api/v1/rules/3", strings.NewReader(body))
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Update invalid rule", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindRulesMethod = func(ids ...string) ([]waukeen.Rule, error) {
+			return []waukeen.Rule{{ID: "3", Type: waukeen.TagRule, Match: "dominos", Result: "pizza"}}, nil
+		}
+		db.UpdateRuleMethod = func(r *waukeen.Rule) error {
+			t.Errorf("wants invalid rule not to be saved")
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		body := `{"mode": "regex", "match": "dominos ("}`
+		req := httptest.NewRequest("PUT", "/api/v1/rules/3", strings.NewReader(body))
+		res := serverTest(srv, req)
+
+		code := 400
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
+
+func TestAPIStatements(t *testing.T) {
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/statements", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		importers := &mock.StatementsImporters{}
+		importers.FindMethod = func(format, filename string, head []byte) (string, waukeen.StatementsImporter, error) {
+			return "", nil, errors.New("unknown statement format")
+		}
+		srv := &Server{StatementsImporters: importers}
+
+		req := filesUpload("statement", "/api/v1/statements", nil, "bank.pdf")
+		res := serverTest(srv, req)
+
+		code := 400
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Import statements", func(t *testing.T) {
+		importer := &mock.StatementsImporter{}
+		importer.ImportMethod = func(io.Reader) ([]waukeen.Statement, error) {
+			return []waukeen.Statement{{Account: waukeen.Account{Number: "123"}}}, nil
+		}
+		importers := &mock.StatementsImporters{}
+		importers.FindMethod = func(format, filename string, head []byte) (string, waukeen.StatementsImporter, error) {
+			return "ofx", importer, nil
+		}
+		db := &mock.Database{}
+		db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+			return fn(db)
+		}
+		db.CreateStatementMethod = func(stmt waukeen.Statement, tr waukeen.TransactionTransformer) error {
+			if tr == nil {
+				t.Errorf("wants rules transformer, got none")
+			}
+			stmt.Import.ID = "1"
+			stmt.Import.Created = 2
+			return nil
+		}
+		srv := &Server{DB: db, StatementsImporters: importers,
+			Transformer: &mock.TransactionTransformer{}}
+
+		req := filesUpload("statement", "/api/v1/statements", nil, "bank.ofx")
+		res := serverTest(srv, req)
+
+		code := 201
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		var got []waukeen.Import
+		json.NewDecoder(res.Body).Decode(&got)
+		if len(got) != 1 || got[0].ID != "1" || got[0].Filename != "bank.ofx" || got[0].Created != 2 {
+			t.Errorf("wants bank.ofx import, got %+v", got)
+		}
+	})
+
+	t.Run("Training error after import", func(t *testing.T) {
+		importer := &mock.StatementsImporter{}
+		importer.ImportMethod = func(io.Reader) ([]waukeen.Statement, error) {
+			return []waukeen.Statement{{Account: waukeen.Account{Number: "123"}}}, nil
+		}
+		importers := &mock.StatementsImporters{}
+		importers.FindMethod = func(format, filename string, head []byte) (string, waukeen.StatementsImporter, error) {
+			return "ofx", importer, nil
+		}
+		db := &mock.Database{}
+		db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+			return fn(db)
+		}
+		db.CreateStatementMethod = func(stmt waukeen.Statement, tr waukeen.TransactionTransformer) error {
+			stmt.Import.ID = "1"
+			return nil
+		}
+		db.FindTransactionsMethod = func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			return nil, errors.New("not implemented")
+		}
+		srv := &Server{DB: db, StatementsImporters: importers,
+			Transformer: &mock.TransactionTransformer{}, Classifier: &tagClassifier{}}
+
+		req := filesUpload("statement", "/api/v1/statements", nil, "bank.ofx")
+		res := serverTest(srv, req)
+
+		code := 201
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
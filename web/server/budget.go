@@ -0,0 +1,147 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/web"
+	"github.com/pkg/errors"
+)
+
+const monthFormat = "2006-01"
+
+// budgetPage shows the envelopes of a month on /budget/2006-01, Moves are the
+// ones made in the month.
+type budgetPage struct {
+	waukeen.EnvelopeMonth
+	Moves    []waukeen.EnvelopeMove
+	Previous string
+	Next     string
+}
+
+// budget serves the envelope budget of a month and the forms assigning money
+// to envelopes and moving it between them.
+func (srv *Server) budget(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path[len("/budget/"):]
+	if path == "" {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.Redirect(w, r, "/budget/"+time.Now().Format(monthFormat), http.StatusFound)
+		return
+	}
+
+	parts := strings.SplitN(path, "/", 2)
+	month, err := time.Parse(monthFormat, parts[0])
+	if err != nil {
+		srv.renderNotFound(w)
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	method := "POST"
+	if action == "" {
+		method = "GET"
+	}
+	if r.Method != method {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch action {
+	case "":
+		srv.envelopes(w, month)
+		return
+	case "assign":
+		err = srv.assignEnvelope(r, month)
+	case "move":
+		err = srv.moveEnvelope(r, month)
+	case "moves/delete":
+		err = srv.DB.DeleteEnvelopeMove(r.FormValue("id"))
+	default:
+		srv.renderNotFound(w)
+		return
+	}
+
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/budget/"+month.Format(monthFormat), http.StatusFound)
+}
+
+func (srv *Server) envelopes(w http.ResponseWriter, month time.Time) {
+	opt := waukeen.TransactionsDBOptions{End: month.AddDate(0, 1, -1)}
+	transactions, err := srv.DB.FindTransactions(opt)
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	tags, err := srv.DB.AllTags()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	ledger, err := srv.DB.FindEnvelopeLedger()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	content := budgetPage{
+		EnvelopeMonth: srv.EnvelopeCalculator.Envelopes(month, transactions, tags, *ledger),
+		Previous:      month.AddDate(0, -1, 0).Format(monthFormat),
+		Next:          month.AddDate(0, 1, 0).Format(monthFormat),
+	}
+
+	for _, m := range ledger.Moves {
+		if m.Month.Format(monthFormat) == month.Format(monthFormat) {
+			content.Moves = append(content.Moves, m)
+		}
+	}
+
+	page := web.Page{
+		Title:      "Budget " + month.Format("January 2006"),
+		ActiveMenu: "budget",
+		Content:    content,
+		Partials:   []string{"budget"},
+	}
+	srv.render(w, page)
+}
+
+func (srv *Server) assignEnvelope(r *http.Request, month time.Time) error {
+	n, err := parseCents(r.FormValue("amount"))
+	if err != nil {
+		return errors.Wrap(err, "invalid assigned amount")
+	}
+
+	return srv.DB.SetEnvelopeAssignment(&waukeen.EnvelopeAssignment{
+		Tag:    r.FormValue("tag"),
+		Month:  month,
+		Amount: n,
+	})
+}
+
+func (srv *Server) moveEnvelope(r *http.Request, month time.Time) error {
+	n, err := parseCents(r.FormValue("amount"))
+	if err != nil {
+		return errors.Wrap(err, "invalid moved amount")
+	}
+
+	return srv.DB.CreateEnvelopeMove(&waukeen.EnvelopeMove{
+		From:   r.FormValue("from"),
+		To:     r.FormValue("to"),
+		Month:  month,
+		Amount: n,
+	})
+}
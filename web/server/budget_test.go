@@ -0,0 +1,187 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/mock"
+)
+
+func TestBudget(t *testing.T) {
+	may := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Current month", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/budget/", nil)
+		res := serverTest(nil, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		url := "/budget/" + time.Now().Format("2006-01")
+		loc := res.Header().Get("Location")
+		if url != loc {
+			t.Errorf("wants %s redirect url, got %s", url, loc)
+		}
+	})
+
+	t.Run("Invalid month", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/budget/may", nil)
+		res := serverTest(nil, req)
+
+		code := 404
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/budget/2024-05", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionsMethod = func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			return nil, errors.New("not implemented")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/budget/2024-05", nil)
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Get month", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionsMethod = func(opt waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			end := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+			if !opt.Start.IsZero() || !opt.End.Equal(end) {
+				t.Errorf("wants transactions until %s, got %+v", end, opt)
+			}
+			return nil, nil
+		}
+		db.AllTagsMethod = func() ([]waukeen.Tag, error) {
+			return nil, nil
+		}
+		db.FindEnvelopeLedgerMethod = func() (*waukeen.EnvelopeLedger, error) {
+			return &waukeen.EnvelopeLedger{}, nil
+		}
+
+		calc := &mock.EnvelopeCalculator{}
+		calc.EnvelopesMethod = func(m time.Time, trs []waukeen.Transaction,
+			tags []waukeen.Tag, l waukeen.EnvelopeLedger) waukeen.EnvelopeMonth {
+			if !m.Equal(may) {
+				t.Errorf("wants month %s, got %s", may, m)
+			}
+			return waukeen.EnvelopeMonth{Month: m}
+		}
+
+		srv := &Server{DB: db, EnvelopeCalculator: calc}
+
+		req := httptest.NewRequest("GET", "/budget/2024-05", nil)
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Assign", func(t *testing.T) {
+		want := &waukeen.EnvelopeAssignment{Tag: "food", Month: may, Amount: 40000}
+
+		db := &mock.Database{}
+		db.SetEnvelopeAssignmentMethod = func(a *waukeen.EnvelopeAssignment) error {
+			if !reflect.DeepEqual(want, a) {
+				t.Errorf("wants %+v, got %+v", want, a)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/budget/2024-05/assign", nil)
+		req.Form = url.Values{"tag": {"food"}, "amount": {"400"}}
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		url := "/budget/2024-05"
+		loc := res.Header().Get("Location")
+		if url != loc {
+			t.Errorf("wants %s redirect url, got %s", url, loc)
+		}
+	})
+
+	t.Run("Invalid amount", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/budget/2024-05/assign", nil)
+		req.Form = url.Values{"tag": {"food"}, "amount": {"a lot"}}
+		res := serverTest(&Server{DB: &mock.Database{}}, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Move", func(t *testing.T) {
+		want := &waukeen.EnvelopeMove{From: "food", To: "fun", Month: may, Amount: 5000}
+
+		db := &mock.Database{}
+		db.CreateEnvelopeMoveMethod = func(m *waukeen.EnvelopeMove) error {
+			if !reflect.DeepEqual(want, m) {
+				t.Errorf("wants %+v, got %+v", want, m)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/budget/2024-05/move", nil)
+		req.Form = url.Values{"from": {"food"}, "to": {"fun"}, "amount": {"50"}}
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Delete move", func(t *testing.T) {
+		db := &mock.Database{}
+		db.DeleteEnvelopeMoveMethod = func(id string) error {
+			if id != "3" {
+				t.Errorf("wants move id 3, got %s", id)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/budget/2024-05/moves/delete", nil)
+		req.Form = url.Values{"id": {"3"}}
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
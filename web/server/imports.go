@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/web"
+)
+
+func (srv *Server) imports(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		imports, err := srv.DB.FindImports()
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
+		accs, err := srv.DB.FindAccounts()
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
+		accounts := make(map[string]waukeen.Account)
+		for _, a := range accs {
+			accounts[a.ID] = a
+		}
+
+		content := struct {
+			Imports  []waukeen.Import
+			Accounts map[string]waukeen.Account
+		}{
+			Imports:  imports,
+			Accounts: accounts,
+		}
+
+		page := web.Page{
+			Title:      "Imports",
+			ActiveMenu: "imports",
+			Content:    content,
+			Partials:   []string{"imports"},
+		}
+		srv.render(w, page)
+	case "POST":
+		id := r.FormValue("id")
+		if id == "" {
+			srv.renderNotFound(w)
+			return
+		}
+
+		err := srv.DB.DeleteImport(id)
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
+		http.Redirect(w, r, "/imports/", http.StatusFound)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/mock"
+)
+
+func TestImports(t *testing.T) {
+	db := &mock.Database{}
+	db.FindImportsMethod = func() ([]waukeen.Import, error) {
+		return []waukeen.Import{{ID: "1", AccountID: "1"}}, nil
+	}
+	db.FindAccountsMethod = func(...string) ([]waukeen.Account, error) {
+		return []waukeen.Account{{ID: "1", Number: "12345"}}, nil
+	}
+	srv := &Server{DB: db}
+
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/imports/", nil)
+		res := serverTest(srv, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Get imports", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/imports/", nil)
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Get imports DB error", func(t *testing.T) {
+		db.FindImportsMethod = func() ([]waukeen.Import, error) {
+			return nil, errors.New("not implemented")
+		}
+
+		req := httptest.NewRequest("GET", "/imports/", nil)
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Delete import DB error", func(t *testing.T) {
+		db.DeleteImportMethod = func(string) error {
+			return errors.New("invalid import id")
+		}
+
+		req := httptest.NewRequest("POST", "/imports/", nil)
+		req.Form = url.Values{}
+		req.Form.Set("id", "99")
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Delete import", func(t *testing.T) {
+		db.DeleteImportMethod = func(id string) error {
+			if id != "1" {
+				t.Errorf("wants import id 1, got %s", id)
+			}
+			return nil
+		}
+
+		req := httptest.NewRequest("POST", "/imports/", nil)
+		req.Form = url.Values{}
+		req.Form.Set("id", "1")
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		url := "/imports/"
+		loc := res.Header().Get("Location")
+
+		if url != loc {
+			t.Errorf("wants %s redirect url, got %s", url, loc)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/web"
+	"github.com/pkg/errors"
+)
+
+// ruleChange is how re-running rules would change a stored transaction.
+type ruleChange struct {
+	Transaction  waukeen.Transaction
+	Alias        string
+	Tags         []string
+	AliasChanged bool
+	TagsChanged  bool
+}
+
+type rerunScope struct {
+	Accounts []string
+	Start    string
+	End      string
+	Rule     string
+}
+
+type rerunPage struct {
+	Accounts  []waukeen.Account
+	Rules     []waukeen.Rule
+	Scope     rerunScope
+	Preview   bool
+	Changes   []ruleChange
+	Protected int
+}
+
+// rerunRules applies rules again to transactions already imported, limited to
+// accounts, a date range or a single rule. Changes are previewed first and
+// only the selected ones are saved, exactly as previewed.
+func (srv *Server) rerunRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	page := rerunPage{
+		Scope: rerunScope{
+			Accounts: r.Form["accounts"],
+			Start:    r.FormValue("start"),
+			End:      r.FormValue("end"),
+			Rule:     r.FormValue("rule"),
+		},
+	}
+
+	page.Accounts, err = srv.DB.FindAccounts()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	page.Rules, err = srv.DB.FindRules()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	if r.Method == "GET" {
+		srv.renderRerun(w, page)
+		return
+	}
+
+	if r.FormValue("apply") != "" {
+		err = srv.applyRuleChanges(r)
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+		http.Redirect(w, r, "/rules/", http.StatusFound)
+		return
+	}
+
+	opts := waukeen.TransactionsDBOptions{Accounts: page.Scope.Accounts}
+
+	opts.Start, err = parseDate(page.Scope.Start, false)
+	if err != nil {
+		srv.renderError(w, errors.Wrap(err, "invalid start date"))
+		return
+	}
+
+	opts.End, err = parseDate(page.Scope.End, true)
+	if err != nil {
+		srv.renderError(w, errors.Wrap(err, "invalid end date"))
+		return
+	}
+
+	rules := page.Rules
+	if page.Scope.Rule != "" {
+		rules, err = srv.DB.FindRules(page.Scope.Rule)
+		if err == nil && len(rules) == 0 {
+			err = errors.New("invalid rule id")
+		}
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+	}
+
+	trs, err := srv.DB.FindTransactions(opts)
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	page.Changes, page.Protected = ruleChanges(trs, rules, srv.Transformer)
+	page.Preview = true
+	srv.renderRerun(w, page)
+}
+
+// applyRuleChanges saves the alias and tags previewed for each selected
+// transaction as they were posted back, without running the rules again.
+func (srv *Server) applyRuleChanges(r *http.Request) error {
+	return srv.DB.WithTx(func(db waukeen.Database) error {
+		for _, id := range r.Form["ids"] {
+			t, err := db.FindTransaction(id)
+			if err != nil {
+				return err
+			}
+			t.Alias = r.FormValue("alias-" + id)
+			t.Tags = splitTags(r.FormValue("tags-" + id))
+			err = db.UpdateTransaction(t)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (srv *Server) renderRerun(w http.ResponseWriter, page rerunPage) {
+	srv.render(w, web.Page{
+		Title:      "Re-run Rules",
+		ActiveMenu: "rules",
+		Content:    page,
+		Partials:   []string{"rerun_rules"},
+	})
+}
+
+// rulesOnly hides a TagClassifier from ApplyRules, so only rules run.
+type rulesOnly struct {
+	waukeen.TransactionTransformer
+}
+
+// ruleChanges runs the rules over each transaction and lists the ones that
+// would change. Aliases and tags edited by hand are kept, protected counts
+// the transactions that would have changed otherwise. Suggested tags are
+// not rule changes and never applied.
+func ruleChanges(trs []waukeen.Transaction, rules []waukeen.Rule,
+	transformer waukeen.TransactionTransformer) (changes []ruleChange, protected int) {
+
+	rules = waukeen.CompileRules(rules)
+	transformer = rulesOnly{transformer}
+	for _, t := range trs {
+		after := t
+		after.Tags = append([]string(nil), t.Tags...)
+		waukeen.ApplyRules(&after, rules, transformer)
+
+		c := ruleChange{
+			Transaction:  t,
+			Alias:        t.Alias,
+			Tags:         t.Tags,
+			AliasChanged: after.Alias != t.Alias,
+			TagsChanged:  !sameTags(after.Tags, t.Tags),
+		}
+
+		kept := false
+		if c.AliasChanged && t.AliasEdited {
+			c.AliasChanged = false
+			kept = true
+		}
+		if c.TagsChanged && t.TagsEdited {
+			c.TagsChanged = false
+			kept = true
+		}
+		if kept {
+			protected++
+		}
+
+		if c.AliasChanged {
+			c.Alias = after.Alias
+		}
+		if c.TagsChanged {
+			c.Tags = after.Tags
+		}
+		if c.AliasChanged || c.TagsChanged {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes, protected
+}
@@ -0,0 +1,286 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/mock"
+)
+
+func rerunTransformer() *mock.TransactionTransformer {
+	transformer := &mock.TransactionTransformer{}
+	transformer.TransformMethod = func(t *waukeen.Transaction, r waukeen.Rule) bool {
+		if !strings.Contains(t.Title, r.Match) {
+			return false
+		}
+		switch r.Type {
+		case waukeen.ReplaceRule:
+			t.Alias = r.Result
+		case waukeen.TagRule:
+			t.AddTags(r.Result)
+		}
+		return true
+	}
+	return transformer
+}
+
+func TestRuleChanges(t *testing.T) {
+	rules := []waukeen.Rule{
+		{Type: waukeen.ReplaceRule, Match: "DOMINOS", Result: "Dominos"},
+		{Type: waukeen.TagRule, Match: "DOMINOS", Result: "pizza"},
+	}
+
+	trs := []waukeen.Transaction{
+		{ID: "1", Title: "DOMINOS 123"},
+		{ID: "2", Title: "DOMINOS 456", Alias: "Dominos", Tags: []string{"pizza"}},
+		{ID: "3", Title: "DOMINOS 789", Alias: "Date night", AliasEdited: true},
+		{ID: "4", Title: "DOMINOS 000", Alias: "Lunch", AliasEdited: true,
+			Tags: []string{"work"}, TagsEdited: true},
+		{ID: "5", Title: "UBER"},
+	}
+
+	want := []ruleChange{
+		{
+			Transaction:  trs[0],
+			Alias:        "Dominos",
+			Tags:         []string{"pizza"},
+			AliasChanged: true,
+			TagsChanged:  true,
+		},
+		{
+			Transaction: trs[2],
+			Alias:       "Date night",
+			Tags:        []string{"pizza"},
+			TagsChanged: true,
+		},
+	}
+
+	got, protected := ruleChanges(trs, rules, rerunTransformer())
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants\n%+v\ngot\n%+v", want, got)
+	}
+
+	if protected != 2 {
+		t.Errorf("wants 2 protected transactions, got %d", protected)
+	}
+
+	if trs[0].Alias != "" || trs[0].Tags != nil {
+		t.Errorf("wants transactions untouched, got %+v", trs[0])
+	}
+}
+
+func TestRuleChangesWithoutSuggestions(t *testing.T) {
+	rules := []waukeen.Rule{
+		{Type: waukeen.ReplaceRule, Match: "DOMINOS", Result: "Dominos"},
+	}
+	trs := []waukeen.Transaction{{ID: "1", Title: "DOMINOS 123"}}
+
+	c := &suggestingClassifier{tagClassifier{TransactionTransformer: *rerunTransformer()}}
+	got, _ := ruleChanges(trs, rules, c)
+
+	want := []ruleChange{
+		{Transaction: trs[0], Alias: "Dominos", AliasChanged: true},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants\n%+v\ngot\n%+v", want, got)
+	}
+}
+
+// suggestingClassifier is confident every transaction is food.
+type suggestingClassifier struct {
+	tagClassifier
+}
+
+func (*suggestingClassifier) Suggest(*waukeen.Transaction) []waukeen.TagSuggestion {
+	return []waukeen.TagSuggestion{{Tag: "food", Confidence: 1, Confident: true}}
+}
+
+func TestRerunRules(t *testing.T) {
+	newDB := func() *mock.Database {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(...string) ([]waukeen.Account, error) {
+			return nil, nil
+		}
+		db.FindRulesMethod = func(ids ...string) ([]waukeen.Rule, error) {
+			rules := []waukeen.Rule{
+				{ID: "1", Type: waukeen.ReplaceRule, Match: "DOMINOS", Result: "Dominos"},
+				{ID: "2", Type: waukeen.TagRule, Match: "DOMINOS", Result: "pizza"},
+			}
+			if len(ids) > 0 {
+				for _, r := range rules {
+					if r.ID == ids[0] {
+						return []waukeen.Rule{r}, nil
+					}
+				}
+				return nil, nil
+			}
+			return rules, nil
+		}
+		db.FindTransactionsMethod = func(opts waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			return []waukeen.Transaction{
+				{ID: "1", Title: "DOMINOS 123"},
+				{ID: "2", Title: "DOMINOS 456"},
+			}, nil
+		}
+		db.FindTransactionMethod = func(id string) (*waukeen.Transaction, error) {
+			return &waukeen.Transaction{ID: id, Title: "DOMINOS " + id,
+				Alias: "Pizza", Tags: []string{"food"}, AliasEdited: true}, nil
+		}
+		db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+			return fn(db)
+		}
+		return db
+	}
+
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/rules/rerun", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Form", func(t *testing.T) {
+		srv := &Server{DB: newDB()}
+
+		req := httptest.NewRequest("GET", "/rules/rerun", nil)
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Preview does not save", func(t *testing.T) {
+		db := newDB()
+		db.FindTransactionsMethod = func(opts waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			want := waukeen.TransactionsDBOptions{
+				Accounts: []string{"1"},
+				Start:    time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+				End:      time.Date(2016, 6, 30, 0, 0, 0, 0, time.UTC),
+			}
+			if !reflect.DeepEqual(want, opts) {
+				t.Errorf("wants %+v, got %+v", want, opts)
+			}
+			return nil, nil
+		}
+		db.UpdateTransactionMethod = func(*waukeen.Transaction) error {
+			t.Errorf("wants no update on preview")
+			return nil
+		}
+		srv := &Server{DB: db, Transformer: rerunTransformer()}
+
+		req := httptest.NewRequest("POST", "/rules/rerun", nil)
+		req.Form = url.Values{
+			"accounts": {"1"},
+			"start":    {"2016-01-01"},
+			"end":      {"2016-06"},
+			"preview":  {"1"},
+		}
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Invalid rule", func(t *testing.T) {
+		srv := &Server{DB: newDB(), Transformer: rerunTransformer()}
+
+		req := httptest.NewRequest("POST", "/rules/rerun", nil)
+		req.Form = url.Values{"rule": {"99"}}
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Apply previewed changes", func(t *testing.T) {
+		db := newDB()
+		db.FindTransactionsMethod = func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			t.Errorf("wants previewed changes applied, not run again")
+			return nil, nil
+		}
+		var updated []waukeen.Transaction
+		db.UpdateTransactionMethod = func(tr *waukeen.Transaction) error {
+			updated = append(updated, *tr)
+			return nil
+		}
+		srv := &Server{DB: db, Transformer: rerunTransformer()}
+
+		req := httptest.NewRequest("POST", "/rules/rerun", nil)
+		req.Form = url.Values{
+			"rule":    {"2"},
+			"ids":     {"2", "3"},
+			"alias-2": {"Pizza"},
+			"tags-2":  {"food, pizza"},
+			"alias-3": {""},
+			"tags-3":  {""},
+			"alias-4": {"Not selected"},
+			"apply":   {"1"},
+		}
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		want := []waukeen.Transaction{
+			{ID: "2", Title: "DOMINOS 2", Alias: "Pizza", Tags: []string{"food", "pizza"},
+				AliasEdited: true},
+			{ID: "3", Title: "DOMINOS 3", AliasEdited: true},
+		}
+		if !reflect.DeepEqual(want, updated) {
+			t.Errorf("wants %+v, got %+v", want, updated)
+		}
+	})
+
+	t.Run("Apply not found", func(t *testing.T) {
+		db := newDB()
+		db.FindTransactionMethod = func(string) (*waukeen.Transaction, error) {
+			return nil, errors.New("not found")
+		}
+		srv := &Server{DB: db, Transformer: rerunTransformer()}
+
+		req := httptest.NewRequest("POST", "/rules/rerun", nil)
+		req.Form = url.Values{"ids": {"9"}, "apply": {"1"}}
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Apply DB error", func(t *testing.T) {
+		db := newDB()
+		db.UpdateTransactionMethod = func(*waukeen.Transaction) error {
+			return errors.New("not implemented")
+		}
+		srv := &Server{DB: db, Transformer: rerunTransformer()}
+
+		req := httptest.NewRequest("POST", "/rules/rerun", nil)
+		req.Form = url.Values{"ids": {"1"}, "apply": {"1"}}
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
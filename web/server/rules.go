@@ -1,11 +1,15 @@
 package server
 
 import (
+	"bytes"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/luizbranco/waukeen"
 	"github.com/luizbranco/waukeen/web"
+	"github.com/pkg/errors"
 )
 
 func (srv *Server) newRule(w http.ResponseWriter, r *http.Request) {
@@ -14,17 +18,119 @@ func (srv *Server) newRule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	page := web.Page{
-		Title:    "New Rule",
-		Partials: []string{"new_rule"},
+	accounts, err := srv.DB.FindAccounts()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	srv.renderRule(w, rulePage{Accounts: accounts})
+}
+
+// rulePage fills the rule form, Test holds the transactions the rule matches
+// when it was tried out.
+type rulePage struct {
+	Accounts []waukeen.Account
+	Rule     waukeen.Rule
+	Test     *ruleTest
+}
+
+type ruleTest struct {
+	Total    int
+	Accounts []ruleTestAccount
+	Matches  []ruleMatch
+}
+
+type ruleTestAccount struct {
+	Account waukeen.Account
+	Count   int
+}
+
+type ruleMatch struct {
+	Before waukeen.Transaction
+	After  waukeen.Transaction
+}
+
+// renderRule shows the new rule form, or the edit form for saved rules.
+func (srv *Server) renderRule(w http.ResponseWriter, page rulePage) {
+	if page.Rule.Conditions == nil {
+		page.Rule.Conditions = &waukeen.RuleConditions{}
+	}
+
+	title, partial := "New Rule", "new_rule"
+	if page.Rule.ID != "" {
+		title, partial = "Edit Rule", "edit_rule"
+	}
+
+	srv.render(w, web.Page{
+		Title:    title,
+		Content:  page,
+		Partials: []string{partial, "rule_form", "rule_test"},
+	})
+}
+
+// testRule runs a rule that is not saved yet over every stored transaction.
+func (srv *Server) testRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	rule, err := ruleForm(r)
+	if err == nil {
+		err = rule.Validate()
+	}
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+	rule.ID = r.FormValue("id")
+
+	accounts, err := srv.DB.FindAccounts()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	trs, err := srv.DB.FindTransactions(waukeen.TransactionsDBOptions{})
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	test := &ruleTest{}
+	counts := make(map[string]int)
+
+	for _, t := range trs {
+		after := t
+		after.Tags = append([]string(nil), t.Tags...)
+		if !srv.Transformer.Transform(&after, *rule) {
+			continue
+		}
+		test.Matches = append(test.Matches, ruleMatch{Before: t, After: after})
+		counts[t.AccountID]++
+	}
+
+	test.Total = len(test.Matches)
+	for _, acc := range accounts {
+		if n := counts[acc.ID]; n > 0 {
+			test.Accounts = append(test.Accounts, ruleTestAccount{Account: acc, Count: n})
+		}
 	}
 
-	srv.render(w, page)
+	srv.renderRule(w, rulePage{Accounts: accounts, Rule: *rule, Test: test})
+}
+
+type rulesPage struct {
+	Rules    []waukeen.Rule
+	Analysis ruleAnalysis
 }
 
 func (srv *Server) rules(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case "GET":
+	id := r.URL.Path[len("/rules/"):]
+
+	switch {
+	case r.Method == "GET" && id == "":
 		rules, err := srv.DB.FindRules()
 
 		if err != nil {
@@ -32,27 +138,61 @@ func (srv *Server) rules(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		months := defaultAnalysisMonths
+		if m, err := strconv.Atoi(r.FormValue("months")); err == nil && m > 0 {
+			months = m
+		}
+
+		opts := waukeen.TransactionsDBOptions{
+			Start: time.Now().AddDate(0, -months, 0),
+		}
+
+		trs, err := srv.DB.FindTransactions(opts)
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
+		tags, err := srv.DB.AllTags()
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
+		analysis := analyzeRules(rules, trs, tags, srv.Transformer)
+		analysis.Months = months
+
 		page := web.Page{
 			Title:      "Rules",
 			ActiveMenu: "rules",
-			Content:    rules,
+			Content:    rulesPage{Rules: rules, Analysis: analysis},
 			Partials:   []string{"rules"},
 		}
 
 		srv.render(w, page)
-	case "POST":
-		t := r.FormValue("type")
-		n, err := strconv.Atoi(t)
+	case r.Method == "GET":
+		rule, err := srv.findRule(id)
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+		if rule == nil {
+			srv.renderNotFound(w)
+			return
+		}
 
+		accounts, err := srv.DB.FindAccounts()
 		if err != nil {
 			srv.renderError(w, err)
 			return
 		}
 
-		rule := &waukeen.Rule{
-			Type:   waukeen.RuleType(n),
-			Match:  r.FormValue("match"),
-			Result: r.FormValue("result"),
+		srv.renderRule(w, rulePage{Accounts: accounts, Rule: *rule})
+	case r.Method == "POST" && id == "":
+		rule, err := ruleForm(r)
+		if err != nil {
+			srv.renderError(w, err)
+			return
 		}
 
 		err = srv.DB.CreateRule(rule)
@@ -62,12 +202,106 @@ func (srv *Server) rules(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		http.Redirect(w, r, "/rules/", http.StatusFound)
+	case r.Method == "POST":
+		saved, err := srv.findRule(id)
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+		if saved == nil {
+			srv.renderNotFound(w)
+			return
+		}
+
+		rule, err := ruleForm(r)
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+		rule.ID = saved.ID
+		rule.Priority = saved.Priority
+
+		err = srv.DB.UpdateRule(rule)
+
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
 		http.Redirect(w, r, "/rules/", http.StatusFound)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
 
+// findRule returns nil when there is no rule with the id.
+func (srv *Server) findRule(id string) (*waukeen.Rule, error) {
+	rules, err := srv.DB.FindRules(id)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	return &rules[0], nil
+}
+
+// deleteRules deletes the selected rules, one from its edit page or many from
+// the rules page.
+func (srv *Server) deleteRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	err = srv.DB.WithTx(func(db waukeen.Database) error {
+		for _, id := range r.Form["ids"] {
+			err := db.DeleteRule(id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/rules/", http.StatusFound)
+}
+
+// reorderRules saves the order rules were dragged into on the rules page.
+func (srv *Server) reorderRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	err = srv.DB.ReorderRules(r.Form["ids"])
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/rules/", http.StatusFound)
+}
+
+// importRules adds the rules from an uploaded file. In merge mode rules
+// already saved with the same type, match and result are skipped, in replace
+// mode every saved rule is deleted first. Priorities in the file are ignored,
+// imported rules come after the saved ones in file order.
 func (srv *Server) importRules(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
@@ -83,6 +317,15 @@ func (srv *Server) importRules(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		mode := r.FormValue("mode")
+		if mode == "" {
+			mode = "merge"
+		}
+		if mode != "merge" && mode != "replace" {
+			srv.renderError(w, errors.Errorf("invalid import mode %q", mode))
+			return
+		}
+
 		rules, err := srv.RulesImporter.Import(file)
 
 		if err != nil {
@@ -90,14 +333,42 @@ func (srv *Server) importRules(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		for _, r := range rules {
-			err := srv.DB.CreateRule(&r)
-
+		err = srv.DB.WithTx(func(db waukeen.Database) error {
+			saved, err := db.FindRules()
 			if err != nil {
-				srv.renderError(w, err)
-				return
+				return err
 			}
 
+			seen := make(map[string]bool)
+			for _, r := range saved {
+				if mode == "replace" {
+					err := db.DeleteRule(r.ID)
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				seen[ruleKey(r)] = true
+			}
+
+			for _, r := range rules {
+				if seen[ruleKey(r)] {
+					continue
+				}
+				seen[ruleKey(r)] = true
+
+				r.Priority = 0
+				err := db.CreateRule(&r)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			srv.renderError(w, err)
+			return
 		}
 
 		http.Redirect(w, r, "/rules", http.StatusFound)
@@ -105,3 +376,109 @@ func (srv *Server) importRules(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
+
+// ruleKey identifies duplicated rules when importing.
+func ruleKey(r waukeen.Rule) string {
+	return strconv.Itoa(int(r.Type)) + "\x1f" + r.Match + "\x1f" + r.Result
+}
+
+// exportRules downloads every rule in priority order as a file importRules
+// reads back.
+func (srv *Server) exportRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	rules, err := srv.DB.FindRules()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	err = srv.RulesExporter.Export(buf, rules)
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="rules.json"`)
+	buf.WriteTo(w)
+}
+
+// ruleForm reads a rule from the form values, amounts are given in dollars
+// and empty conditions are left unset.
+func ruleForm(r *http.Request) (*waukeen.Rule, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return nil, err
+	}
+
+	ints := make(map[string]int)
+	for _, key := range []string{"type", "mode", "field", "transaction_type",
+		"first_day", "last_day"} {
+
+		v := r.FormValue(key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", key)
+		}
+		ints[key] = n
+	}
+
+	rule := &waukeen.Rule{
+		Type:   waukeen.RuleType(ints["type"]),
+		Mode:   waukeen.MatchMode(ints["mode"]),
+		Field:  waukeen.RuleField(ints["field"]),
+		Match:  r.FormValue("match"),
+		Result: r.FormValue("result"),
+		Stop:   r.FormValue("stop") != "",
+	}
+
+	c := &waukeen.RuleConditions{
+		Accounts:        r.Form["accounts"],
+		TransactionType: waukeen.TransactionType(ints["transaction_type"]),
+		FirstDay:        ints["first_day"],
+		LastDay:         ints["last_day"],
+	}
+
+	c.MinAmount, err = parseCents(r.FormValue("min_amount"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid min amount")
+	}
+
+	c.MaxAmount, err = parseCents(r.FormValue("max_amount"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid max amount")
+	}
+
+	for _, d := range r.Form["weekdays"] {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid weekday")
+		}
+		c.Weekdays = append(c.Weekdays, time.Weekday(n))
+	}
+
+	if !c.Empty() {
+		rule.Conditions = c
+	}
+
+	return rule, nil
+}
+
+func parseCents(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(f * 100)), nil
+}
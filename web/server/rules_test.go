@@ -7,11 +7,13 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/luizbranco/waukeen"
 	"github.com/luizbranco/waukeen/mock"
+	"github.com/luizbranco/waukeen/web"
 )
 
 func TestNewRule(t *testing.T) {
@@ -25,9 +27,31 @@ func TestNewRule(t *testing.T) {
 		}
 	})
 
+	t.Run("DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(...string) ([]waukeen.Account, error) {
+			return nil, errors.New("not implemented")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/rules/new", nil)
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
 	t.Run("Valid Method", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(...string) ([]waukeen.Account, error) {
+			return nil, nil
+		}
+		srv := &Server{DB: db}
+
 		req := httptest.NewRequest("GET", "/rules/new", nil)
-		res := serverTest(nil, req)
+		res := serverTest(srv, req)
 
 		code := 200
 		if res.Code != code {
@@ -36,6 +60,109 @@ func TestNewRule(t *testing.T) {
 	})
 }
 
+func TestTestRule(t *testing.T) {
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/rules/test", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Invalid rule", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/rules/test", nil)
+		req.Form = url.Values{"type": {"1"}, "mode": {"1"}, "match": {"("}}
+		res := serverTest(nil, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(...string) ([]waukeen.Account, error) {
+			return nil, nil
+		}
+		db.FindTransactionsMethod = func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			return nil, errors.New("not implemented")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/rules/test", nil)
+		req.Form = url.Values{"type": {"2"}, "match": {"dominos"}, "result": {"pizza"}}
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Matches", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindAccountsMethod = func(...string) ([]waukeen.Account, error) {
+			return []waukeen.Account{{ID: "1"}, {ID: "2"}, {ID: "3"}}, nil
+		}
+		db.FindTransactionsMethod = func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			return []waukeen.Transaction{
+				{ID: "1", AccountID: "1", Title: "DOMINOS 123"},
+				{ID: "2", AccountID: "1", Title: "GROCERY"},
+				{ID: "3", AccountID: "2", Title: "DOMINOS 456"},
+				{ID: "4", AccountID: "1", Title: "DOMINOS 789"},
+			}, nil
+		}
+
+		transformer := &mock.TransactionTransformer{}
+		transformer.TransformMethod = func(t *waukeen.Transaction, r waukeen.Rule) bool {
+			if t.Title == "GROCERY" {
+				return false
+			}
+			t.Tags = append(t.Tags, r.Result)
+			return true
+		}
+
+		var page web.Page
+		tpl := &mock.Template{}
+		tpl.RenderMethod = func(w io.Writer, p web.Page) error {
+			page = p
+			return nil
+		}
+
+		srv := &Server{DB: db, Transformer: transformer, Template: tpl}
+
+		req := httptest.NewRequest("POST", "/rules/test", nil)
+		req.Form = url.Values{"type": {"2"}, "match": {"dominos"}, "result": {"pizza"}}
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Fatalf("wants %d status code, got %d", code, res.Code)
+		}
+
+		test := page.Content.(rulePage).Test
+		if test == nil || test.Total != 3 {
+			t.Fatalf("wants 3 matches, got %+v", test)
+		}
+
+		accounts := []ruleTestAccount{
+			{Account: waukeen.Account{ID: "1"}, Count: 2},
+			{Account: waukeen.Account{ID: "2"}, Count: 1},
+		}
+		if !reflect.DeepEqual(accounts, test.Accounts) {
+			t.Errorf("wants %+v, got %+v", accounts, test.Accounts)
+		}
+
+		m := test.Matches[0]
+		if len(m.Before.Tags) != 0 || !reflect.DeepEqual(m.After.Tags, []string{"pizza"}) {
+			t.Errorf("wants tags added only after, got %+v", m)
+		}
+	})
+}
+
 func TestRules(t *testing.T) {
 	t.Run("Invalid Method", func(t *testing.T) {
 		req := httptest.NewRequest("DELETE", "/rules/", nil)
@@ -63,20 +190,63 @@ func TestRules(t *testing.T) {
 		}
 	})
 
-	t.Run("Get rules", func(t *testing.T) {
+	t.Run("Get rules transactions DB error", func(t *testing.T) {
 		db := &mock.Database{}
 		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
 			return nil, nil
 		}
+		db.FindTransactionsMethod = func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			return nil, errors.New("not implemented")
+		}
 		srv := &Server{DB: db}
 
 		req := httptest.NewRequest("GET", "/rules/", nil)
 		res := serverTest(srv, req)
 
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Get rules", func(t *testing.T) {
+		rules := []waukeen.Rule{{ID: "1", Type: waukeen.TagRule, Match: "TACO", Result: "restaurants"}}
+
+		db := &mock.Database{}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return rules, nil
+		}
+		db.FindTransactionsMethod = func(opts waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			start := time.Now().AddDate(0, -3, -1)
+			if opts.Start.Before(start) {
+				t.Errorf("wants transactions from the last 3 months, got %s", opts.Start)
+			}
+			return []waukeen.Transaction{{ID: "1", Title: "PIZZA"}}, nil
+		}
+		db.AllTagsMethod = func() ([]waukeen.Tag, error) {
+			return []waukeen.Tag{{Name: "restaurants"}}, nil
+		}
+
+		var page web.Page
+		tpl := &mock.Template{}
+		tpl.RenderMethod = func(w io.Writer, p web.Page) error {
+			page = p
+			return nil
+		}
+		srv := &Server{DB: db, Template: tpl, Transformer: rerunTransformer()}
+
+		req := httptest.NewRequest("GET", "/rules/?months=3", nil)
+		res := serverTest(srv, req)
+
 		code := 200
 		if res.Code != code {
 			t.Errorf("wants %d status code, got %d", code, res.Code)
 		}
+
+		content := page.Content.(rulesPage)
+		if !reflect.DeepEqual(rules, content.Analysis.Dead) || content.Analysis.Months != 3 {
+			t.Errorf("wants dead rule analysis over 3 months, got %+v", content.Analysis)
+		}
 	})
 
 	t.Run("Post new rule invalid type", func(t *testing.T) {
@@ -121,7 +291,7 @@ func TestRules(t *testing.T) {
 		db := &mock.Database{}
 		db.CreateRuleMethod = func(r *waukeen.Rule) error {
 			if !reflect.DeepEqual(r, rule) {
-				t.Errorf("want %s, got %s", rule, r)
+				t.Errorf("want %+v, got %+v", rule, r)
 			}
 			return nil
 		}
@@ -147,6 +317,283 @@ func TestRules(t *testing.T) {
 			t.Errorf("wants %s redirect url, got %s", url, loc)
 		}
 	})
+
+	t.Run("Get rule not found", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return nil, nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/rules/99", nil)
+		res := serverTest(srv, req)
+
+		code := 404
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Get rule", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindRulesMethod = func(ids ...string) ([]waukeen.Rule, error) {
+			if !reflect.DeepEqual([]string{"1"}, ids) {
+				t.Errorf("wants rule 1, got %v", ids)
+			}
+			return []waukeen.Rule{{ID: "1"}}, nil
+		}
+		db.FindAccountsMethod = func(...string) ([]waukeen.Account, error) {
+			return nil, nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/rules/1", nil)
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Update rule not found", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return nil, nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/rules/99", nil)
+		res := serverTest(srv, req)
+
+		code := 404
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Update rule DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return []waukeen.Rule{{ID: "1"}}, nil
+		}
+		db.UpdateRuleMethod = func(*waukeen.Rule) error {
+			return errors.New("not implemented")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/rules/1", nil)
+		req.Form = url.Values{}
+		req.Form.Set("type", "1")
+
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Update rule", func(t *testing.T) {
+		rule := &waukeen.Rule{
+			ID:       "1",
+			Type:     waukeen.ReplaceRule,
+			Match:    "dominos",
+			Result:   "Dominos",
+			Priority: 4,
+			Stop:     true,
+		}
+
+		db := &mock.Database{}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return []waukeen.Rule{{ID: "1", Type: waukeen.TagRule, Priority: 4}}, nil
+		}
+		db.UpdateRuleMethod = func(r *waukeen.Rule) error {
+			if !reflect.DeepEqual(r, rule) {
+				t.Errorf("want %+v, got %+v", rule, r)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/rules/1", nil)
+		req.Form = url.Values{}
+		req.Form.Set("type", strconv.Itoa(int(rule.Type)))
+		req.Form.Set("match", rule.Match)
+		req.Form.Set("result", rule.Result)
+		req.Form.Set("stop", "1")
+
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		url := "/rules/"
+		loc := res.Header().Get("Location")
+
+		if url != loc {
+			t.Errorf("wants %s redirect url, got %s", url, loc)
+		}
+	})
+}
+
+func TestDeleteRules(t *testing.T) {
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/rules/delete", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.DeleteRuleMethod = func(string) error {
+			return errors.New("invalid rule id")
+		}
+		db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+			return fn(db)
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/rules/delete", nil)
+		req.Form = url.Values{"ids": {"99"}}
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		var deleted []string
+		db := &mock.Database{}
+		db.DeleteRuleMethod = func(id string) error {
+			deleted = append(deleted, id)
+			return nil
+		}
+		db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+			return fn(db)
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/rules/delete", nil)
+		req.Form = url.Values{"ids": {"1", "3"}}
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		want := []string{"1", "3"}
+		if !reflect.DeepEqual(want, deleted) {
+			t.Errorf("wants %v deleted, got %v", want, deleted)
+		}
+	})
+}
+
+func TestReorderRules(t *testing.T) {
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/rules/reorder", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.ReorderRulesMethod = func([]string) error {
+			return errors.New("invalid rule id")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/rules/reorder", nil)
+		req.Form = url.Values{"ids": {"99"}}
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Reorder", func(t *testing.T) {
+		db := &mock.Database{}
+		db.ReorderRulesMethod = func(ids []string) error {
+			want := []string{"3", "1", "2"}
+			if !reflect.DeepEqual(want, ids) {
+				t.Errorf("wants %v, got %v", want, ids)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/rules/reorder", nil)
+		req.Form = url.Values{"ids": {"3", "1", "2"}}
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
+
+func TestRuleForm(t *testing.T) {
+	req := httptest.NewRequest("POST", "/rules/", nil)
+	req.Form = url.Values{
+		"type":             {"2"},
+		"mode":             {"2"},
+		"match":            {"amazon"},
+		"result":           {"electronics"},
+		"min_amount":       {"500"},
+		"max_amount":       {"1200.50"},
+		"accounts":         {"12345", "67890"},
+		"transaction_type": {"2"},
+		"weekdays":         {"6", "0"},
+		"first_day":        {""},
+		"last_day":         {"15"},
+	}
+
+	want := &waukeen.Rule{
+		Type:   waukeen.TagRule,
+		Mode:   waukeen.PrefixMatch,
+		Match:  "amazon",
+		Result: "electronics",
+		Conditions: &waukeen.RuleConditions{
+			MinAmount:       50000,
+			MaxAmount:       120050,
+			Accounts:        []string{"12345", "67890"},
+			TransactionType: waukeen.Debit,
+			Weekdays:        []time.Weekday{time.Saturday, time.Sunday},
+			LastDay:         15,
+		},
+	}
+
+	got, err := ruleForm(req)
+	if err != nil {
+		t.Errorf("wants no error, got %s", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants %+v, got %+v", want, got)
+	}
+
+	t.Run("Invalid amount", func(t *testing.T) {
+		req.Form.Set("min_amount", "five")
+		_, err := ruleForm(req)
+		if err == nil {
+			t.Errorf("wants error, got none")
+		}
+	})
 }
 
 func TestImportRules(t *testing.T) {
@@ -192,9 +639,15 @@ func TestImportRules(t *testing.T) {
 			return []waukeen.Rule{waukeen.Rule{}}, nil
 		}
 		db := &mock.Database{}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return nil, nil
+		}
 		db.CreateRuleMethod = func(r *waukeen.Rule) error {
 			return errors.New("not implemented")
 		}
+		db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+			return fn(db)
+		}
 		srv := &Server{RulesImporter: importer, DB: db}
 
 		req := fileUpload("rules", "/rules/import")
@@ -214,15 +667,23 @@ func TestImportRules(t *testing.T) {
 		}
 		importer := &mock.RulesImporter{}
 		importer.ImportMethod = func(io.Reader) ([]waukeen.Rule, error) {
-			return []waukeen.Rule{*rule}, nil
+			r := *rule
+			r.Priority = 7
+			return []waukeen.Rule{r}, nil
 		}
 		db := &mock.Database{}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return nil, nil
+		}
 		db.CreateRuleMethod = func(r *waukeen.Rule) error {
 			if !reflect.DeepEqual(r, rule) {
-				t.Errorf("want %s, got %s", rule, r)
+				t.Errorf("want %+v, got %+v", rule, r)
 			}
 			return nil
 		}
+		db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+			return fn(db)
+		}
 		srv := &Server{RulesImporter: importer, DB: db}
 
 		req := fileUpload("rules", "/rules/import")
@@ -240,4 +701,133 @@ func TestImportRules(t *testing.T) {
 			t.Errorf("wants %s redirect url, got %s", url, loc)
 		}
 	})
+
+	t.Run("Post invalid mode", func(t *testing.T) {
+		req := fileUpload("rules", "/rules/import?mode=append")
+		res := serverTest(nil, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	imported := []waukeen.Rule{
+		{Type: waukeen.TagRule, Match: "dominos", Result: "pizza"},
+		{Type: waukeen.TagRule, Match: "uber", Result: "transportation"},
+		{Type: waukeen.TagRule, Match: "uber", Result: "transportation"},
+	}
+	saved := []waukeen.Rule{
+		{ID: "1", Type: waukeen.TagRule, Match: "dominos", Result: "pizza"},
+		{ID: "2", Type: waukeen.ReplaceRule, Match: "dominos", Result: "pizza"},
+	}
+
+	for _, tc := range []struct {
+		mode    string
+		deleted []string
+		created []string
+	}{
+		{"merge", nil, []string{"uber"}},
+		{"replace", []string{"1", "2"}, []string{"dominos", "uber"}},
+	} {
+		t.Run("Post "+tc.mode, func(t *testing.T) {
+			importer := &mock.RulesImporter{}
+			importer.ImportMethod = func(io.Reader) ([]waukeen.Rule, error) {
+				return imported, nil
+			}
+
+			var deleted, created []string
+			db := &mock.Database{}
+			db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+				return saved, nil
+			}
+			db.DeleteRuleMethod = func(id string) error {
+				deleted = append(deleted, id)
+				return nil
+			}
+			db.CreateRuleMethod = func(r *waukeen.Rule) error {
+				created = append(created, r.Match)
+				return nil
+			}
+			db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+				return fn(db)
+			}
+			srv := &Server{RulesImporter: importer, DB: db}
+
+			req := fileUpload("rules", "/rules/import?mode="+tc.mode)
+			res := serverTest(srv, req)
+
+			code := 302
+			if res.Code != code {
+				t.Errorf("wants %d status code, got %d", code, res.Code)
+			}
+			if !reflect.DeepEqual(tc.deleted, deleted) {
+				t.Errorf("wants %v deleted, got %v", tc.deleted, deleted)
+			}
+			if !reflect.DeepEqual(tc.created, created) {
+				t.Errorf("wants %v created, got %v", tc.created, created)
+			}
+		})
+	}
+}
+
+func TestExportRules(t *testing.T) {
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/rules/export", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return nil, errors.New("not implemented")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/rules/export", nil)
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		rules := []waukeen.Rule{{ID: "1", Type: waukeen.TagRule, Match: "dominos", Result: "pizza"}}
+		db := &mock.Database{}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return rules, nil
+		}
+		exporter := &mock.RulesExporter{}
+		exporter.ExportMethod = func(w io.Writer, got []waukeen.Rule) error {
+			if !reflect.DeepEqual(rules, got) {
+				t.Errorf("wants %+v, got %+v", rules, got)
+			}
+			_, err := io.WriteString(w, "[]")
+			return err
+		}
+		srv := &Server{DB: db, RulesExporter: exporter}
+
+		req := httptest.NewRequest("GET", "/rules/export", nil)
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		disposition := `attachment; filename="rules.json"`
+		if got := res.Header().Get("Content-Disposition"); got != disposition {
+			t.Errorf("wants %s, got %s", disposition, got)
+		}
+		if body := res.Body.String(); body != "[]" {
+			t.Errorf("wants [] body, got %s", body)
+		}
+	})
 }
@@ -9,12 +9,19 @@ import (
 )
 
 type Server struct {
-	DB                 waukeen.Database
-	Template           web.Template
-	StatementsImporter waukeen.StatementsImporter
-	RulesImporter      waukeen.RulesImporter
-	Transformer        waukeen.TransactionTransformer
-	BudgetCalculator   waukeen.BudgetCalculator
+	DB                  waukeen.Database
+	Template            web.Template
+	StatementsImporters waukeen.StatementsImporters
+	RulesImporter       waukeen.RulesImporter
+	RulesExporter       waukeen.RulesExporter
+	Transformer         waukeen.TransactionTransformer
+	BudgetCalculator    waukeen.BudgetCalculator
+	EnvelopeCalculator  waukeen.EnvelopeCalculator
+
+	// Classifier suggests tags when set, usually it is the Transformer too.
+	Classifier waukeen.TagClassifier
+
+	uploads uploads
 }
 
 func (srv *Server) NewServeMux() *http.ServeMux {
@@ -24,16 +31,28 @@ func (srv *Server) NewServeMux() *http.ServeMux {
 	mux.Handle("/assets/", http.StripPrefix("/assets/", fs))
 
 	mux.HandleFunc("/accounts/", srv.accounts)
+	mux.HandleFunc("/budget/", srv.budget)
+	mux.HandleFunc("/imports/", srv.imports)
+	mux.HandleFunc("/rules/delete", srv.deleteRules)
+	mux.HandleFunc("/rules/export", srv.exportRules)
 	mux.HandleFunc("/rules/import", srv.importRules)
 	mux.HandleFunc("/rules/new", srv.newRule)
+	mux.HandleFunc("/rules/reorder", srv.reorderRules)
+	mux.HandleFunc("/rules/rerun", srv.rerunRules)
+	mux.HandleFunc("/rules/suggestions", srv.ruleSuggestions)
+	mux.HandleFunc("/rules/test", srv.testRule)
 	mux.HandleFunc("/rules/", srv.rules)
 	mux.HandleFunc("/statements/new", srv.newStatement)
 	mux.HandleFunc("/statements", srv.createStatement)
+	mux.HandleFunc("/statements/", srv.statementPreview)
 	mux.HandleFunc("/tags/new", srv.newTag)
+	mux.HandleFunc("/tags/train", srv.trainTags)
 	mux.HandleFunc("/tags/", srv.tags)
 	mux.HandleFunc("/transactions/", srv.transactions)
 	mux.HandleFunc("/", srv.index)
 
+	srv.apiMux(mux)
+
 	return mux
 }
 
@@ -61,3 +61,33 @@ func fileUpload(name, uri string) *http.Request {
 
 	return req
 }
+
+func filesUpload(name, uri string, values map[string]string,
+	files ...string) *http.Request {
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for _, f := range files {
+		_, err := writer.CreateFormFile(name, f)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for k, v := range values {
+		err := writer.WriteField(k, v)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	writer.Close()
+
+	req, err := http.NewRequest("POST", uri, body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
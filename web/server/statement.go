@@ -1,9 +1,14 @@
 package server
 
 import (
+	"bufio"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 
+	"github.com/luizbranco/waukeen"
 	"github.com/luizbranco/waukeen/web"
+	"github.com/pkg/errors"
 )
 
 func (srv *Server) newStatement(w http.ResponseWriter, r *http.Request) {
@@ -13,6 +18,7 @@ func (srv *Server) newStatement(w http.ResponseWriter, r *http.Request) {
 	}
 	page := web.Page{
 		Title:    "Import Statement",
+		Content:  srv.StatementsImporters.Formats(),
 		Partials: []string{"statement"},
 	}
 	srv.render(w, page)
@@ -21,32 +27,220 @@ func (srv *Server) newStatement(w http.ResponseWriter, r *http.Request) {
 func (srv *Server) createStatement(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
 	}
 
-	file, _, err := r.FormFile("statement")
+	err := r.ParseMultipartForm(32 << 20)
 	if err != nil {
 		srv.renderError(w, err)
 		return
 	}
 
-	list, err := srv.StatementsImporter.Import(file)
+	files := r.MultipartForm.File["statement"]
+	if len(files) == 0 {
+		srv.renderError(w, errors.New("no statement file uploaded"))
+		return
+	}
+
+	format := r.FormValue("format")
+	account := r.FormValue("account")
+
+	var list []waukeen.Statement
+
+	for _, fh := range files {
+		stmts, err := srv.importStatement(fh, format, account)
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+		list = append(list, stmts...)
+	}
+
+	if len(list) == 0 {
+		srv.renderError(w, errors.New("no statements found"))
+		return
+	}
+
+	rules, err := srv.DB.FindRules()
 	if err != nil {
 		srv.renderError(w, err)
 		return
 	}
+	rules = waukeen.CompileRules(rules)
 
-	if len(list) == 0 {
+	preview := make([]statementPreview, len(list))
+
+	for i, stmt := range list {
+		preview[i], err = srv.previewStatement(stmt, rules)
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+	}
+
+	token, err := srv.uploads.put(preview)
+	if err != nil {
 		srv.renderError(w, err)
 		return
 	}
 
-	for _, item := range list {
-		err = srv.DB.CreateStatement(item, srv.Transformer)
+	http.Redirect(w, r, "/statements/"+token, http.StatusFound)
+}
+
+func (srv *Server) statementPreview(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Path[len("/statements/"):]
+
+	up, ok := srv.uploads.get(token)
+	if !ok {
+		srv.renderNotFound(w)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		page := web.Page{
+			Title:    "Review Statement",
+			Content:  up,
+			Partials: []string{"statement_preview"},
+		}
+		srv.render(w, page)
+	case "POST":
+		err := r.ParseForm()
 		if err != nil {
 			srv.renderError(w, err)
 			return
 		}
+
+		if r.FormValue("cancel") != "" {
+			srv.uploads.remove(token)
+			http.Redirect(w, r, "/statements/new", http.StatusFound)
+			return
+		}
+
+		include := make(map[string]bool)
+		for _, id := range r.Form["include"] {
+			include[id] = true
+		}
+
+		err = srv.DB.WithTx(func(db waukeen.Database) error {
+			for i, sp := range up.Statements {
+				imp := sp.Import
+				stmt := waukeen.Statement{Account: sp.Account, Import: &imp}
+
+				for j, row := range sp.Rows {
+					id := fmt.Sprintf("%d-%d", i, j)
+					if row.Duplicate || !include[id] {
+						imp.Skipped++
+						continue
+					}
+					t := row.Transaction
+					if tags, ok := r.Form["tags-"+id]; ok {
+						t.Tags = splitTags(tags[0])
+					}
+					stmt.Transactions = append(stmt.Transactions, t)
+				}
+
+				err := db.CreateStatement(stmt, nil)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
+		srv.uploads.remove(token)
+		srv.retrainClassifier()
+
+		http.Redirect(w, r, "/accounts", http.StatusFound)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// previewStatement runs the rules over the statement transactions and flags
+// the ones already imported for the same account. Rules conditioned on the
+// account match by its number, known even when the account is new.
+func (srv *Server) previewStatement(stmt waukeen.Statement,
+	rules []waukeen.Rule) (statementPreview, error) {
+
+	preview := statementPreview{Account: stmt.Account}
+	if stmt.Import != nil {
+		preview.Import = *stmt.Import
+	}
+	existing := make(map[string]bool)
+
+	acc, err := srv.DB.FindAccount(stmt.Account.Number)
+	if err != nil {
+		acc = nil
+	}
+
+	if acc != nil && len(stmt.Transactions) > 0 {
+		opts := waukeen.TransactionsDBOptions{Accounts: []string{acc.ID}}
+		for _, t := range stmt.Transactions {
+			opts.FITIDs = append(opts.FITIDs, t.FITID)
+		}
+
+		trs, err := srv.DB.FindTransactions(opts)
+		if err != nil {
+			return preview, err
+		}
+
+		for _, t := range trs {
+			existing[t.FITID] = true
+		}
+	}
+
+	for _, t := range stmt.Transactions {
+		t.AccountNumber = stmt.Account.Number
+		if acc != nil {
+			t.AccountID = acc.ID
+		}
+		waukeen.ApplyRules(&t, rules, srv.Transformer)
+		preview.Rows = append(preview.Rows, transactionPreview{
+			Transaction: t,
+			Duplicate:   existing[t.FITID],
+		})
+	}
+
+	return preview, nil
+}
+
+// importStatement reads the statements of an uploaded file, files that don't
+// say which account they are from get the account number given, if any.
+func (srv *Server) importStatement(fh *multipart.FileHeader,
+	format, account string) ([]waukeen.Statement, error) {
+
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buf := bufio.NewReader(file)
+	head, _ := buf.Peek(512)
+
+	name, importer, err := srv.StatementsImporters.Find(format, fh.Filename, head)
+	if err != nil {
+		return nil, err
+	}
+
+	if a, ok := importer.(waukeen.AccountStatementsImporter); ok && account != "" {
+		importer = a.WithAccount(account)
+	}
+
+	stmts, err := importer.Import(buf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "import %s", fh.Filename)
+	}
+
+	for i := range stmts {
+		stmts[i].Import = &waukeen.Import{Filename: fh.Filename, Importer: name}
 	}
 
-	http.Redirect(w, r, "/accounts", http.StatusFound)
+	return stmts, nil
 }
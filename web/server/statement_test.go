@@ -3,6 +3,9 @@ package server
 import (
 	"io"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -12,9 +15,15 @@ import (
 )
 
 func TestNewStatement(t *testing.T) {
+	importers := &mock.StatementsImporters{}
+	importers.FormatsMethod = func() []string {
+		return []string{"ofx", "qif"}
+	}
+	srv := &Server{StatementsImporters: importers}
+
 	t.Run("Invalid Method", func(t *testing.T) {
 		req := httptest.NewRequest("POST", "/statements/new", nil)
-		res := serverTest(nil, req)
+		res := serverTest(srv, req)
 
 		code := 405
 		if res.Code != code {
@@ -24,7 +33,7 @@ func TestNewStatement(t *testing.T) {
 
 	t.Run("Valid Method", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/statements/new", nil)
-		res := serverTest(nil, req)
+		res := serverTest(srv, req)
 
 		code := 200
 		if res.Code != code {
@@ -35,11 +44,18 @@ func TestNewStatement(t *testing.T) {
 
 func TestCreateStatement(t *testing.T) {
 	importer := &mock.StatementsImporter{}
+	importers := &mock.StatementsImporters{}
+	importers.FindMethod = func(string, string, []byte) (string, waukeen.StatementsImporter, error) {
+		return "ofx", importer, nil
+	}
 	db := &mock.Database{}
+	db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+		return fn(db)
+	}
 
 	srv := &Server{
-		StatementsImporter: importer,
-		DB:                 db,
+		StatementsImporters: importers,
+		DB:                  db,
 	}
 
 	t.Run("Invalid Method", func(t *testing.T) {
@@ -78,15 +94,15 @@ func TestCreateStatement(t *testing.T) {
 		}
 	})
 
-	t.Run("Statement Error", func(t *testing.T) {
+	t.Run("Rules DB Error", func(t *testing.T) {
 		importer.ImportMethod = func(io.Reader) ([]waukeen.Statement, error) {
 			return []waukeen.Statement{{
 				Account: waukeen.Account{Number: "12345"},
 			}}, nil
 		}
 
-		db.CreateStatementMethod = func(waukeen.Statement, waukeen.TransactionTransformer) error {
-			return errors.New("account not found")
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return nil, errors.New("not implemented")
 		}
 
 		req := fileUpload("statement", "/statements")
@@ -98,14 +114,23 @@ func TestCreateStatement(t *testing.T) {
 		}
 	})
 
-	t.Run("Statement(s) Successfully Imported", func(t *testing.T) {
+	t.Run("Statement(s) Successfully Uploaded", func(t *testing.T) {
 		importer.ImportMethod = func(io.Reader) ([]waukeen.Statement, error) {
 			return []waukeen.Statement{{
 				Account: waukeen.Account{Number: "12345"},
 			}}, nil
 		}
 
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return nil, nil
+		}
+
+		db.FindAccountMethod = func(string) (*waukeen.Account, error) {
+			return nil, errors.New("account not found")
+		}
+
 		db.CreateStatementMethod = func(waukeen.Statement, waukeen.TransactionTransformer) error {
+			t.Errorf("wants statement to wait for confirmation")
 			return nil
 		}
 
@@ -117,11 +142,290 @@ func TestCreateStatement(t *testing.T) {
 			t.Errorf("wants %d status code, got %d (%s)", code, res.Code, res.Body)
 		}
 
-		url := "/accounts"
 		loc := res.Header().Get("Location")
+		if !strings.HasPrefix(loc, "/statements/") || len(loc) == len("/statements/") {
+			t.Errorf("wants preview redirect url, got %s", loc)
+		}
+	})
+
+	t.Run("Unknown Format", func(t *testing.T) {
+		importers.FindMethod = func(string, string, []byte) (string, waukeen.StatementsImporter, error) {
+			return "", nil, errors.New("unknown format")
+		}
+
+		req := fileUpload("statement", "/statements")
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d (%s)", code, res.Code, res.Body)
+		}
+	})
+
+	t.Run("Multiple Files", func(t *testing.T) {
+		var formats, filenames []string
+		importers.FindMethod = func(format, filename string, head []byte) (string, waukeen.StatementsImporter, error) {
+			formats = append(formats, format)
+			filenames = append(filenames, filename)
+			return format, importer, nil
+		}
+
+		importer.ImportMethod = func(io.Reader) ([]waukeen.Statement, error) {
+			return []waukeen.Statement{{
+				Account: waukeen.Account{Number: "12345"},
+			}}, nil
+		}
+
+		values := map[string]string{"format": "qif"}
+		req := filesUpload("statement", "/statements", values, "visa.qif", "savings.qif")
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d (%s)", code, res.Code, res.Body)
+		}
+
+		want := []string{"visa.qif", "savings.qif"}
+		if !reflect.DeepEqual(want, filenames) {
+			t.Errorf("wants %v files, got %v", want, filenames)
+		}
+
+		want = []string{"qif", "qif"}
+		if !reflect.DeepEqual(want, formats) {
+			t.Errorf("wants %v formats, got %v", want, formats)
+		}
+	})
+
+	t.Run("Account For Files Without One", func(t *testing.T) {
+		var accounts []string
+		qif := &mock.AccountStatementsImporter{}
+		qif.WithAccountMethod = func(number string) waukeen.StatementsImporter {
+			accounts = append(accounts, number)
+			return importer
+		}
+
+		importers.FindMethod = func(string, string, []byte) (string, waukeen.StatementsImporter, error) {
+			return "qif", qif, nil
+		}
+
+		values := map[string]string{"account": "12345"}
+		req := filesUpload("statement", "/statements", values, "bank.qif")
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d (%s)", code, res.Code, res.Body)
+		}
+
+		want := []string{"12345"}
+		if !reflect.DeepEqual(want, accounts) {
+			t.Errorf("wants %v accounts, got %v", want, accounts)
+		}
+	})
+}
+
+func TestStatementPreview(t *testing.T) {
+	db := &mock.Database{}
+	db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+		return fn(db)
+	}
+	db.FindAccountMethod = func(number string) (*waukeen.Account, error) {
+		return &waukeen.Account{ID: "1", Number: number}, nil
+	}
+	db.FindTransactionsMethod = func(opts waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+		want := waukeen.TransactionsDBOptions{
+			Accounts: []string{"1"},
+			FITIDs:   []string{"01", "02", "03"},
+		}
+		if !reflect.DeepEqual(want, opts) {
+			t.Errorf("wants %+v, got %+v", want, opts)
+		}
+		return []waukeen.Transaction{{ID: "9", FITID: "02"}}, nil
+	}
+
+	transformer := &mock.TransactionTransformer{}
+	transformer.TransformMethod = func(t *waukeen.Transaction, r waukeen.Rule) bool {
+		t.AddTags(r.Result)
+		return true
+	}
+
+	srv := &Server{DB: db, Transformer: transformer}
 
+	stmt := waukeen.Statement{
+		Account: waukeen.Account{Number: "12345"},
+		Import:  &waukeen.Import{Filename: "bank.ofx", Importer: "ofx"},
+		Transactions: []waukeen.Transaction{
+			{FITID: "01", Title: "Dominos"},
+			{FITID: "02", Title: "Dominos"},
+			{FITID: "03", Title: "Subway"},
+		},
+	}
+
+	preview, err := srv.previewStatement(stmt, []waukeen.Rule{{Result: "food"}})
+	if err != nil {
+		t.Fatalf("wants no error, got %s", err)
+	}
+
+	for i, row := range preview.Rows {
+		if row.Duplicate != (i == 1) {
+			t.Errorf("wants only second row to be a duplicate, got %+v", preview.Rows)
+		}
+		if !reflect.DeepEqual(row.Transaction.Tags, []string{"food"}) {
+			t.Errorf("wants rules to be applied, got %+v", row.Transaction)
+		}
+	}
+
+	t.Run("Unknown Token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/statements/abc", nil)
+		res := serverTest(srv, req)
+
+		code := 404
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Show Preview", func(t *testing.T) {
+		token, err := srv.uploads.put([]statementPreview{preview})
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		req := httptest.NewRequest("GET", "/statements/"+token, nil)
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Cancel Import", func(t *testing.T) {
+		token, err := srv.uploads.put([]statementPreview{preview})
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		req := httptest.NewRequest("POST", "/statements/"+token, nil)
+		req.Form = url.Values{}
+		req.Form.Set("cancel", "Cancel")
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		if _, ok := srv.uploads.get(token); ok {
+			t.Errorf("wants upload to be discarded")
+		}
+	})
+
+	t.Run("Confirm Import", func(t *testing.T) {
+		token, err := srv.uploads.put([]statementPreview{preview})
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+
+		db.CreateStatementMethod = func(got waukeen.Statement, tr waukeen.TransactionTransformer) error {
+			want := waukeen.Statement{
+				Account: waukeen.Account{Number: "12345"},
+				Transactions: []waukeen.Transaction{
+					{AccountID: "1", AccountNumber: "12345", FITID: "03", Title: "Subway",
+						Tags: []string{"restaurants", "lunch"}},
+				},
+				Import: &waukeen.Import{Filename: "bank.ofx", Importer: "ofx", Skipped: 2},
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("wants\n%+v\ngot\n%+v", want, got)
+			}
+			if tr != nil {
+				t.Errorf("wants rules not to be applied twice")
+			}
+			return nil
+		}
+
+		req := httptest.NewRequest("POST", "/statements/"+token, nil)
+		req.Form = url.Values{}
+		req.Form.Add("include", "0-1")
+		req.Form.Add("include", "0-2")
+		req.Form.Set("tags-0-2", "restaurants, lunch")
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		url := "/accounts"
+		loc := res.Header().Get("Location")
 		if url != loc {
 			t.Errorf("wants %s redirect url, got %s", url, loc)
 		}
+
+		if _, ok := srv.uploads.get(token); ok {
+			t.Errorf("wants upload to be discarded")
+		}
 	})
 }
+
+func TestPreviewStatementAccountRules(t *testing.T) {
+	db := &mock.Database{}
+	db.FindAccountMethod = func(number string) (*waukeen.Account, error) {
+		if number != "12345" {
+			return nil, errors.New("account not found")
+		}
+		return &waukeen.Account{ID: "1", Number: number}, nil
+	}
+	db.FindTransactionsMethod = func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+		return nil, nil
+	}
+
+	transformer := &mock.TransactionTransformer{}
+	transformer.TransformMethod = func(t *waukeen.Transaction, r waukeen.Rule) bool {
+		if !r.Conditions.Match(t) {
+			return false
+		}
+		t.AddTags(r.Result)
+		return true
+	}
+
+	srv := &Server{DB: db, Transformer: transformer}
+
+	rules := []waukeen.Rule{
+		{Result: "chequing", Conditions: &waukeen.RuleConditions{Accounts: []string{"12345"}}},
+		{Result: "visa", Conditions: &waukeen.RuleConditions{Accounts: []string{"99999"}}},
+	}
+
+	testCases := []struct {
+		name    string
+		account string
+		id      string
+		want    []string
+	}{
+		{name: "existing account", account: "12345", id: "1", want: []string{"chequing"}},
+		{name: "new account", account: "99999", want: []string{"visa"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stmt := waukeen.Statement{
+				Account:      waukeen.Account{Number: tc.account},
+				Transactions: []waukeen.Transaction{{FITID: "01", Title: "Dominos"}},
+			}
+
+			preview, err := srv.previewStatement(stmt, rules)
+			if err != nil {
+				t.Fatalf("wants no error, got %s", err)
+			}
+
+			got := preview.Rows[0].Transaction
+			if !reflect.DeepEqual(tc.want, got.Tags) {
+				t.Errorf("wants tags %v, got %v", tc.want, got.Tags)
+			}
+			if got.AccountID != tc.id {
+				t.Errorf("wants account id %q, got %q", tc.id, got.AccountID)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/web"
+)
+
+// minEdits is how many transactions must have been edited the same way for
+// a rule to be suggested.
+const minEdits = 3
+
+// ruleSuggestion is a rule that would have made edits done by hand, Titles
+// are the titles of the transactions edited.
+type ruleSuggestion struct {
+	Rule   waukeen.Rule
+	Titles []string
+}
+
+// ruleSuggestions lists rules mined from manual edits on /rules/suggestions,
+// accepting one saves it as a new rule.
+func (srv *Server) ruleSuggestions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		edits, err := srv.DB.FindTransactionEdits()
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
+		rules, err := srv.DB.FindRules()
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
+		page := web.Page{
+			Title:      "Rule Suggestions",
+			ActiveMenu: "rules",
+			Content:    suggestRules(edits, rules),
+			Partials:   []string{"rule_suggestions"},
+		}
+		srv.render(w, page)
+	case "POST":
+		rule, err := ruleForm(r)
+		if err == nil {
+			err = srv.DB.CreateRule(rule)
+		}
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
+		http.Redirect(w, r, "/rules/suggestions", http.StatusFound)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// editKey is an alias or a tag given by hand.
+type editKey struct {
+	alias bool
+	value string
+}
+
+type editCandidate struct {
+	key    editKey
+	token  string
+	titles map[string]string
+}
+
+// suggestRules looks for title words edited the same way on at least
+// minEdits transactions. A word must lead to that alias or tag in most of
+// the edits of the same kind it shows up in. Words found in the very same
+// transactions as a better one are left out, as are rules already saved.
+func suggestRules(edits []waukeen.TransactionEdit, rules []waukeen.Rule) []ruleSuggestion {
+	// only the latest alias of a transaction counts
+	aliases := make(map[string]waukeen.TransactionEdit)
+	for _, e := range edits {
+		if e.Alias != "" {
+			aliases[e.TransactionID] = e
+		}
+	}
+
+	// token -> edit -> transaction id -> title
+	found := make(map[string]map[editKey]map[string]string)
+	// token -> alias or tag edit -> transaction ids
+	totals := make(map[string]map[bool]map[string]bool)
+
+	add := func(key editKey, e waukeen.TransactionEdit) {
+		for _, tk := range titleTokens(e.Title) {
+			if found[tk] == nil {
+				found[tk] = make(map[editKey]map[string]string)
+				totals[tk] = make(map[bool]map[string]bool)
+			}
+			if found[tk][key] == nil {
+				found[tk][key] = make(map[string]string)
+			}
+			if totals[tk][key.alias] == nil {
+				totals[tk][key.alias] = make(map[string]bool)
+			}
+			found[tk][key][e.TransactionID] = e.Title
+			totals[tk][key.alias][e.TransactionID] = true
+		}
+	}
+
+	for _, e := range edits {
+		if e.Tag != "" {
+			add(editKey{value: e.Tag}, e)
+		}
+	}
+	for _, e := range aliases {
+		add(editKey{alias: true, value: e.Alias}, e)
+	}
+
+	var candidates []editCandidate
+	for tk, keys := range found {
+		for key, titles := range keys {
+			n := len(titles)
+			if n >= minEdits && n*2 > len(totals[tk][key.alias]) {
+				candidates = append(candidates, editCandidate{key, tk, titles})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if len(a.titles) != len(b.titles) {
+			return len(a.titles) > len(b.titles)
+		}
+		if a.key != b.key {
+			return a.key.value < b.key.value || a.key.value == b.key.value && a.key.alias
+		}
+		return a.token < b.token
+	})
+
+	var suggestions []ruleSuggestion
+	chosen := make(map[editKey][]editCandidate)
+
+OUTER:
+	for _, c := range candidates {
+		for _, prev := range chosen[c.key] {
+			if subset(c.titles, prev.titles) {
+				continue OUTER
+			}
+		}
+		chosen[c.key] = append(chosen[c.key], c)
+
+		rule := editRule(c)
+		if hasRule(rules, rule) {
+			continue
+		}
+
+		s := ruleSuggestion{Rule: rule}
+		for _, title := range c.titles {
+			s.Titles = append(s.Titles, title)
+		}
+		sort.Strings(s.Titles)
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions
+}
+
+// editRule tags transactions with the word, or replaces their whole title by
+// the alias.
+func editRule(c editCandidate) waukeen.Rule {
+	if !c.key.alias {
+		return waukeen.Rule{Type: waukeen.TagRule, Match: c.token, Result: c.key.value}
+	}
+	return waukeen.Rule{
+		Type:   waukeen.ReplaceRule,
+		Mode:   waukeen.RegexMatch,
+		Match:  `^(.*\s)?` + regexp.QuoteMeta(c.token) + `(\s.*)?$`,
+		Result: c.key.value,
+	}
+}
+
+func hasRule(rules []waukeen.Rule, r waukeen.Rule) bool {
+	for _, saved := range rules {
+		if saved.Type == r.Type && saved.Result == r.Result &&
+			strings.EqualFold(saved.Match, r.Match) {
+			return true
+		}
+	}
+	return false
+}
+
+func subset(a, b map[string]string) bool {
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// titleTokens are the distinct lower case words of a title with at least two
+// characters and a letter, numbers change between transactions.
+func titleTokens(title string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, w := range strings.Fields(strings.ToLower(title)) {
+		if len(w) < 2 || seen[w] || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		seen[w] = true
+		tokens = append(tokens, w)
+	}
+	return tokens
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/mock"
+)
+
+func TestSuggestRules(t *testing.T) {
+	edits := []waukeen.TransactionEdit{
+		{TransactionID: "1", Title: "DOMINOS PIZZA #1", Tag: "restaurants"},
+		{TransactionID: "2", Title: "DOMINOS PIZZA #2", Tag: "restaurants"},
+		{TransactionID: "3", Title: "DOMINOS PIZZA #3", Tag: "restaurants"},
+		{TransactionID: "1", Title: "DOMINOS PIZZA #1", Alias: "Pizza"},
+		{TransactionID: "1", Title: "DOMINOS PIZZA #1", Alias: "Dominos"},
+		{TransactionID: "2", Title: "DOMINOS PIZZA #2", Alias: "Dominos"},
+		{TransactionID: "3", Title: "DOMINOS PIZZA #3", Alias: "Dominos"},
+		{TransactionID: "4", Title: "UBER TRIP", Tag: "transportation"},
+		{TransactionID: "5", Title: "UBER TRIP", Tag: "transportation"},
+		{TransactionID: "6", Title: "UBER EATS", Tag: "restaurants"},
+		{TransactionID: "7", Title: "UBER EATS", Tag: "restaurants"},
+		{TransactionID: "8", Title: "UBER TRIP", Tag: "transportation"},
+		{TransactionID: "9", Title: "STORE 123", Tag: "groceries"},
+		{TransactionID: "10", Title: "STORE 456", Tag: "groceries"},
+		{TransactionID: "11", Title: "STORE 789", Tag: "groceries"},
+	}
+
+	rules := []waukeen.Rule{
+		{Type: waukeen.TagRule, Match: "STORE", Result: "groceries"},
+	}
+
+	want := []ruleSuggestion{
+		{
+			Rule: waukeen.Rule{Type: waukeen.ReplaceRule, Mode: waukeen.RegexMatch,
+				Match: `^(.*\s)?dominos(\s.*)?$`, Result: "Dominos"},
+			Titles: []string{"DOMINOS PIZZA #1", "DOMINOS PIZZA #2", "DOMINOS PIZZA #3"},
+		},
+		{
+			Rule:   waukeen.Rule{Type: waukeen.TagRule, Match: "dominos", Result: "restaurants"},
+			Titles: []string{"DOMINOS PIZZA #1", "DOMINOS PIZZA #2", "DOMINOS PIZZA #3"},
+		},
+		{
+			Rule:   waukeen.Rule{Type: waukeen.TagRule, Match: "trip", Result: "transportation"},
+			Titles: []string{"UBER TRIP", "UBER TRIP", "UBER TRIP"},
+		},
+	}
+
+	got := suggestRules(edits, rules)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants\n%+v\ngot\n%+v", want, got)
+	}
+
+	t.Run("Suggested rule matches", func(t *testing.T) {
+		re, err := got[0].Rule.Pattern()
+		if err != nil {
+			t.Fatalf("wants no error, got %s", err)
+		}
+		if alias := re.ReplaceAllString("DOMINOS PIZZA #4", "Dominos"); alias != "Dominos" {
+			t.Errorf("wants Dominos alias, got %s", alias)
+		}
+	})
+}
+
+func TestRuleSuggestions(t *testing.T) {
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/rules/suggestions", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionEditsMethod = func() ([]waukeen.TransactionEdit, error) {
+			return nil, errors.New("not implemented")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/rules/suggestions", nil)
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Get suggestions", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionEditsMethod = func() ([]waukeen.TransactionEdit, error) {
+			return nil, nil
+		}
+		db.FindRulesMethod = func(...string) ([]waukeen.Rule, error) {
+			return nil, nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("GET", "/rules/suggestions", nil)
+		res := serverTest(srv, req)
+
+		code := 200
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Accept suggestion", func(t *testing.T) {
+		rule := &waukeen.Rule{
+			Type:   waukeen.TagRule,
+			Match:  "dominos",
+			Result: "restaurants",
+		}
+
+		db := &mock.Database{}
+		db.CreateRuleMethod = func(r *waukeen.Rule) error {
+			if !reflect.DeepEqual(r, rule) {
+				t.Errorf("want %+v, got %+v", rule, r)
+			}
+			return nil
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/rules/suggestions", nil)
+		req.Form = url.Values{
+			"type":   {"2"},
+			"mode":   {"0"},
+			"field":  {"0"},
+			"match":  {"dominos"},
+			"result": {"restaurants"},
+		}
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+
+		url := "/rules/suggestions"
+		loc := res.Header().Get("Location")
+
+		if url != loc {
+			t.Errorf("wants %s redirect url, got %s", url, loc)
+		}
+	})
+}
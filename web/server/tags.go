@@ -1,8 +1,11 @@
 package server
 
 import (
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/luizbranco/waukeen"
 	"github.com/luizbranco/waukeen/web"
@@ -36,7 +39,7 @@ func (srv *Server) tags(w http.ResponseWriter, r *http.Request) {
 			page := web.Page{
 				Title:      "Tags",
 				ActiveMenu: "tags",
-				Content:    tags,
+				Content:    tagTree(tags),
 				Partials:   []string{"tags"},
 			}
 			srv.render(w, page)
@@ -64,12 +67,32 @@ func (srv *Server) tags(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		var max int64
+		if c := r.FormValue("rollover_cap"); c != "" {
+			max, err = strconv.ParseInt(c, 10, 64)
+			if err != nil {
+				srv.renderError(w, errors.Wrap(err, "invalid rollover cap number"))
+				return
+			}
+		}
+
+		budgets, err := tagBudgetsForm(r)
+		if err != nil {
+			srv.renderError(w, err)
+			return
+		}
+
 		id := r.FormValue("id")
 
 		tag := &waukeen.Tag{
 			ID:            id,
 			Name:          r.FormValue("name"),
 			MonthlyBudget: n,
+			Rollover:      r.FormValue("rollover") != "",
+			RolloverCap:   max,
+			Budgets:       budgets,
+			Income:        r.FormValue("income") != "",
+			Parent:        strings.TrimSpace(r.FormValue("parent")),
 		}
 
 		if id != "" {
@@ -88,3 +111,112 @@ func (srv *Server) tags(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
+
+// tagNode is a tag of the /tags/ tree, Depth is how far below a root it is.
+type tagNode struct {
+	waukeen.Tag
+	Depth int
+}
+
+// tagTree lists the tags depth first, children sorted by name under their
+// parent.
+func tagTree(tags []waukeen.Tag) []tagNode {
+	tree := waukeen.NewTagTree(tags)
+
+	var nodes []tagNode
+	var walk func(parent string, depth int)
+	walk = func(parent string, depth int) {
+		for _, name := range tree.Children(parent) {
+			tag, _ := tree.Tag(name)
+			nodes = append(nodes, tagNode{tag, depth})
+			walk(name, depth+1)
+		}
+	}
+	walk("", 0)
+
+	return nodes
+}
+
+// tagBudgetsForm reads the month budgets of the tag form, rows without a
+// month are left out.
+func tagBudgetsForm(r *http.Request) ([]waukeen.TagBudget, error) {
+	err := r.ParseForm()
+	if err != nil {
+		return nil, errors.Wrap(err, "parse tag form")
+	}
+
+	months := r.Form["budget_month"]
+	amounts := r.Form["budget_amount"]
+
+	var budgets []waukeen.TagBudget
+	for i, m := range months {
+		if m == "" {
+			continue
+		}
+
+		month, err := time.Parse("2006-01", m)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid budget month")
+		}
+
+		var amount string
+		if i < len(amounts) {
+			amount = amounts[i]
+		}
+		n, err := strconv.ParseInt(amount, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid budget amount")
+		}
+
+		budgets = append(budgets, waukeen.TagBudget{Month: month, Amount: n})
+	}
+	return budgets, nil
+}
+
+// trainTags retrains the tag suggestions on every tagged transaction.
+func (srv *Server) trainTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := srv.trainClassifier()
+	if err != nil {
+		srv.renderError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/tags/", http.StatusFound)
+}
+
+// trainClassifier saves a model trained on the stored transactions and
+// starts suggesting tags with it. It does nothing without a Classifier.
+func (srv *Server) trainClassifier() error {
+	if srv.Classifier == nil {
+		return nil
+	}
+
+	trs, err := srv.DB.FindTransactions(waukeen.TransactionsDBOptions{})
+	if err != nil {
+		return err
+	}
+
+	m := srv.Classifier.Train(trs)
+
+	err = srv.DB.SaveTagModel(m)
+	if err != nil {
+		return errors.Wrap(err, "save tag model")
+	}
+
+	srv.Classifier.Load(m)
+	return nil
+}
+
+// retrainClassifier trains the classifier once transactions were imported.
+// The import already succeeded, a training error is only logged.
+func (srv *Server) retrainClassifier() {
+	err := srv.trainClassifier()
+	if err != nil {
+		log.Printf("train tag classifier: %s", err)
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/mock"
+)
+
+type tagClassifier struct {
+	mock.TransactionTransformer
+	trained []waukeen.Transaction
+	loaded  *waukeen.TagModel
+}
+
+func (c *tagClassifier) Train(trs []waukeen.Transaction) *waukeen.TagModel {
+	c.trained = trs
+	return &waukeen.TagModel{Tags: map[string]int{"pizza": len(trs)}}
+}
+
+func (c *tagClassifier) Load(m *waukeen.TagModel) {
+	c.loaded = m
+}
+
+func (c *tagClassifier) Suggest(*waukeen.Transaction) []waukeen.TagSuggestion {
+	return nil
+}
+
+func TestTrainTags(t *testing.T) {
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/tags/train", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Without classifier", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/tags/train", nil)
+		res := serverTest(nil, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	trs := []waukeen.Transaction{{ID: "1", Tags: []string{"pizza"}}}
+
+	t.Run("DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionsMethod = func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			return trs, nil
+		}
+		db.SaveTagModelMethod = func(*waukeen.TagModel) error {
+			return errors.New("not implemented")
+		}
+		c := &tagClassifier{}
+		srv := &Server{DB: db, Classifier: c}
+
+		req := httptest.NewRequest("POST", "/tags/train", nil)
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+		if c.loaded != nil {
+			t.Errorf("wants model not loaded, got %+v", c.loaded)
+		}
+	})
+
+	t.Run("Train", func(t *testing.T) {
+		var saved *waukeen.TagModel
+		db := &mock.Database{}
+		db.FindTransactionsMethod = func(waukeen.TransactionsDBOptions) ([]waukeen.Transaction, error) {
+			return trs, nil
+		}
+		db.SaveTagModelMethod = func(m *waukeen.TagModel) error {
+			saved = m
+			return nil
+		}
+		c := &tagClassifier{}
+		srv := &Server{DB: db, Classifier: c}
+
+		req := httptest.NewRequest("POST", "/tags/train", nil)
+		res := serverTest(srv, req)
+
+		code := 302
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+		if !reflect.DeepEqual(trs, c.trained) {
+			t.Errorf("wants %+v trained, got %+v", trs, c.trained)
+		}
+		if saved == nil || saved != c.loaded {
+			t.Errorf("wants saved model loaded, got %+v and %+v", saved, c.loaded)
+		}
+	})
+}
+
+func TestTagTree(t *testing.T) {
+	tags := []waukeen.Tag{
+		{Name: "pizza", Parent: "restaurants"},
+		{Name: "rent"},
+		{Name: "restaurants", Parent: "food"},
+		{Name: "food"},
+		{Name: "groceries", Parent: "food"},
+	}
+
+	var got []string
+	for _, n := range tagTree(tags) {
+		got = append(got, strings.Repeat("-", n.Depth)+n.Name)
+	}
+
+	want := []string{"food", "-groceries", "-restaurants", "--pizza", "rent"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("wants %v, got %v", want, got)
+	}
+}
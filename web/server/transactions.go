@@ -10,6 +10,11 @@ import (
 	"github.com/luizbranco/waukeen/web"
 )
 
+type transactionPage struct {
+	*waukeen.Transaction
+	Suggestions []waukeen.TagSuggestion
+}
+
 func (srv *Server) transactions(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
@@ -26,9 +31,13 @@ func (srv *Server) transactions(w http.ResponseWriter, r *http.Request) {
 		if tr.Alias == "" {
 			tr.Alias = tr.Title
 		}
+		content := transactionPage{Transaction: tr}
+		if srv.Classifier != nil {
+			content.Suggestions = srv.Classifier.Suggest(tr)
+		}
 		page := web.Page{
 			Title:    "Transaction",
-			Content:  tr,
+			Content:  content,
 			Partials: []string{"transaction"},
 		}
 		srv.render(w, page)
@@ -43,7 +52,18 @@ func (srv *Server) transactions(w http.ResponseWriter, r *http.Request) {
 			srv.renderNotFound(w)
 			return
 		}
-		tr.Alias = r.FormValue("alias")
+		// the form shows the title when there is no alias, keeping it is
+		// not an edit
+		var edits []waukeen.TransactionEdit
+
+		alias := r.FormValue("alias")
+		if alias != tr.Alias && !(tr.Alias == "" && alias == tr.Title) {
+			tr.AliasEdited = true
+			if alias != "" {
+				edits = append(edits, waukeen.TransactionEdit{Alias: alias})
+			}
+		}
+		tr.Alias = alias
 		tr.Description = r.FormValue("description")
 
 		ttype := r.FormValue("transaction_type")
@@ -54,16 +74,22 @@ func (srv *Server) transactions(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		var tags []string
-		vals := strings.Split(r.FormValue("tags"), ",")
-		for _, t := range vals {
-			tag := strings.Trim(t, " ")
-			if tag != "" {
-				tags = append(tags, tag)
+		tags := splitTags(r.FormValue("tags"))
+		if !sameTags(tags, tr.Tags) {
+			tr.TagsEdited = true
+		}
+		for _, tag := range tags {
+			if !contains(tr.Tags, tag) {
+				edits = append(edits, waukeen.TransactionEdit{Tag: tag})
 			}
 		}
 		tr.Tags = tags
 
+		if r.FormValue("unlock") != "" {
+			tr.AliasEdited = false
+			tr.TagsEdited = false
+		}
+
 		amount := r.FormValue("amount")
 		if amount != "" {
 			i, err := strconv.ParseInt(amount, 10, 64)
@@ -80,7 +106,22 @@ func (srv *Server) transactions(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		err = srv.DB.UpdateTransaction(tr)
+		// edits are kept to suggest rules doing the same on new imports
+		err = srv.DB.WithTx(func(db waukeen.Database) error {
+			err := db.UpdateTransaction(tr)
+			if err != nil {
+				return err
+			}
+			for _, e := range edits {
+				e.TransactionID = tr.ID
+				e.Title = tr.Title
+				err := db.CreateTransactionEdit(&e)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
 
 		if err != nil {
 			srv.renderError(w, err)
@@ -92,3 +133,40 @@ func (srv *Server) transactions(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
+
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool)
+	for _, t := range a {
+		set[t] = true
+	}
+	for _, t := range b {
+		if !set[t] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func splitTags(s string) []string {
+	var tags []string
+	vals := strings.Split(s, ",")
+	for _, t := range vals {
+		tag := strings.Trim(t, " ")
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/luizbranco/waukeen"
+	"github.com/luizbranco/waukeen/mock"
+)
+
+// postTransaction submits the transaction form for a copy of saved and
+// returns the transaction updated along with the edits recorded.
+func postTransaction(t *testing.T, saved waukeen.Transaction,
+	form url.Values) (*waukeen.Transaction, []waukeen.TransactionEdit) {
+
+	var updated *waukeen.Transaction
+	var edits []waukeen.TransactionEdit
+
+	db := &mock.Database{}
+	db.FindTransactionMethod = func(id string) (*waukeen.Transaction, error) {
+		tr := saved
+		tr.Tags = append([]string(nil), saved.Tags...)
+		return &tr, nil
+	}
+	db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+		return fn(db)
+	}
+	db.UpdateTransactionMethod = func(tr *waukeen.Transaction) error {
+		updated = tr
+		return nil
+	}
+	db.CreateTransactionEditMethod = func(e *waukeen.TransactionEdit) error {
+		edits = append(edits, *e)
+		return nil
+	}
+	srv := &Server{DB: db}
+
+	form.Set("id", saved.ID)
+	req := httptest.NewRequest("POST", "/transactions/", nil)
+	req.Form = form
+	res := serverTest(srv, req)
+
+	code := 302
+	if res.Code != code {
+		t.Errorf("wants %d status code, got %d", code, res.Code)
+	}
+
+	return updated, edits
+}
+
+func TestTransactions(t *testing.T) {
+	t.Run("Invalid Method", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/transactions/1", nil)
+		res := serverTest(nil, req)
+
+		code := 405
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Missing id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/transactions/", nil)
+		res := serverTest(nil, req)
+
+		code := 404
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("Update not found", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionMethod = func(string) (*waukeen.Transaction, error) {
+			return nil, errors.New("not found")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/transactions/", nil)
+		req.Form = url.Values{"id": {"9"}}
+		res := serverTest(srv, req)
+
+		code := 404
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+
+	t.Run("DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionMethod = func(id string) (*waukeen.Transaction, error) {
+			return &waukeen.Transaction{ID: id, Title: "DOMINOS"}, nil
+		}
+		db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+			return fn(db)
+		}
+		db.UpdateTransactionMethod = func(*waukeen.Transaction) error {
+			return errors.New("not implemented")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/transactions/", nil)
+		req.Form = url.Values{"id": {"1"}, "alias": {"Dominos"}}
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
+
+func TestUpdateTransactionEditFlags(t *testing.T) {
+	testCases := []struct {
+		name  string
+		saved waukeen.Transaction
+		form  url.Values
+		alias bool
+		tags  bool
+	}{
+		{
+			name:  "unchanged",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS", Alias: "Dominos", Tags: []string{"food"}},
+			form:  url.Values{"alias": {"Dominos"}, "tags": {"food"}},
+		},
+		{
+			name:  "title shown without alias",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS"},
+			form:  url.Values{"alias": {"DOMINOS"}},
+		},
+		{
+			name:  "alias",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS", Alias: "Dominos"},
+			form:  url.Values{"alias": {"Dominos Pizza"}},
+			alias: true,
+		},
+		{
+			name:  "alias given to title",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS"},
+			form:  url.Values{"alias": {"Dominos"}},
+			alias: true,
+		},
+		{
+			name:  "alias cleared",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS", Alias: "Dominos"},
+			form:  url.Values{"alias": {""}},
+			alias: true,
+		},
+		{
+			name:  "tags reordered",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS", Tags: []string{"food", "pizza"}},
+			form:  url.Values{"alias": {"DOMINOS"}, "tags": {"pizza, food"}},
+		},
+		{
+			name:  "tag added",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS", Tags: []string{"food"}},
+			form:  url.Values{"alias": {"DOMINOS"}, "tags": {"food, pizza"}},
+			tags:  true,
+		},
+		{
+			name:  "tag removed",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS", Tags: []string{"food", "pizza"}},
+			form:  url.Values{"alias": {"DOMINOS"}, "tags": {"food"}},
+			tags:  true,
+		},
+		{
+			name: "kept when unchanged",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS", Alias: "Dominos",
+				Tags: []string{"food"}, AliasEdited: true, TagsEdited: true},
+			form:  url.Values{"alias": {"Dominos"}, "tags": {"food"}},
+			alias: true,
+			tags:  true,
+		},
+		{
+			name: "unlocked",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS", Alias: "Dominos",
+				Tags: []string{"food"}, AliasEdited: true, TagsEdited: true},
+			form: url.Values{"alias": {"Dominos"}, "tags": {"food"}, "unlock": {"1"}},
+		},
+		{
+			name:  "unlocked while editing",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS", Alias: "Dominos"},
+			form:  url.Values{"alias": {"Pizza"}, "tags": {"food"}, "unlock": {"1"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _ := postTransaction(t, tc.saved, tc.form)
+			if got == nil {
+				t.Fatalf("wants transaction to be updated")
+			}
+			if got.AliasEdited != tc.alias {
+				t.Errorf("wants alias edited %t, got %t", tc.alias, got.AliasEdited)
+			}
+			if got.TagsEdited != tc.tags {
+				t.Errorf("wants tags edited %t, got %t", tc.tags, got.TagsEdited)
+			}
+		})
+	}
+}
+
+func TestUpdateTransactionEdits(t *testing.T) {
+	saved := waukeen.Transaction{ID: "1", Title: "DOMINOS 123", Alias: "Dominos",
+		Tags: []string{"food"}}
+
+	testCases := []struct {
+		name  string
+		saved waukeen.Transaction
+		form  url.Values
+		want  []waukeen.TransactionEdit
+	}{
+		{
+			name:  "unchanged alias",
+			saved: saved,
+			form:  url.Values{"alias": {"Dominos"}, "tags": {"food"}},
+		},
+		{
+			name:  "title shown without alias",
+			saved: waukeen.Transaction{ID: "1", Title: "DOMINOS 123"},
+			form:  url.Values{"alias": {"DOMINOS 123"}},
+		},
+		{
+			name:  "changed alias",
+			saved: saved,
+			form:  url.Values{"alias": {"Dominos Pizza"}, "tags": {"food"}},
+			want: []waukeen.TransactionEdit{
+				{TransactionID: "1", Title: "DOMINOS 123", Alias: "Dominos Pizza"},
+			},
+		},
+		{
+			name:  "cleared alias",
+			saved: saved,
+			form:  url.Values{"alias": {""}, "tags": {"food"}},
+		},
+		{
+			name:  "new tag",
+			saved: saved,
+			form:  url.Values{"alias": {"Dominos"}, "tags": {"food, pizza"}},
+			want: []waukeen.TransactionEdit{
+				{TransactionID: "1", Title: "DOMINOS 123", Tag: "pizza"},
+			},
+		},
+		{
+			name:  "removed tag",
+			saved: saved,
+			form:  url.Values{"alias": {"Dominos"}, "tags": {""}},
+		},
+		{
+			name:  "alias and tags",
+			saved: saved,
+			form:  url.Values{"alias": {"Pizza"}, "tags": {"pizza, lunch"}},
+			want: []waukeen.TransactionEdit{
+				{TransactionID: "1", Title: "DOMINOS 123", Alias: "Pizza"},
+				{TransactionID: "1", Title: "DOMINOS 123", Tag: "pizza"},
+				{TransactionID: "1", Title: "DOMINOS 123", Tag: "lunch"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, got := postTransaction(t, tc.saved, tc.form)
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("wants edits %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("Edit DB error", func(t *testing.T) {
+		db := &mock.Database{}
+		db.FindTransactionMethod = func(id string) (*waukeen.Transaction, error) {
+			return &waukeen.Transaction{ID: id, Title: "DOMINOS 123"}, nil
+		}
+		db.WithTxMethod = func(fn func(waukeen.Database) error) error {
+			return fn(db)
+		}
+		db.UpdateTransactionMethod = func(*waukeen.Transaction) error {
+			return nil
+		}
+		db.CreateTransactionEditMethod = func(*waukeen.TransactionEdit) error {
+			return errors.New("not implemented")
+		}
+		srv := &Server{DB: db}
+
+		req := httptest.NewRequest("POST", "/transactions/", nil)
+		req.Form = url.Values{"id": {"1"}, "tags": {"pizza"}}
+		res := serverTest(srv, req)
+
+		code := 500
+		if res.Code != code {
+			t.Errorf("wants %d status code, got %d", code, res.Code)
+		}
+	})
+}
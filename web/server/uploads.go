@@ -0,0 +1,86 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/luizbranco/waukeen"
+)
+
+const uploadTTL = 30 * time.Minute
+
+// upload is a parsed statement file waiting to be confirmed by the user.
+type upload struct {
+	Token      string
+	Statements []statementPreview
+	expires    time.Time
+}
+
+type statementPreview struct {
+	Account waukeen.Account
+	Import  waukeen.Import
+	Rows    []transactionPreview
+}
+
+type transactionPreview struct {
+	Transaction waukeen.Transaction
+	Duplicate   bool
+}
+
+type uploads struct {
+	sync.Mutex
+	pending map[string]*upload
+}
+
+func (u *uploads) put(stmts []statementPreview) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	token := hex.EncodeToString(b)
+
+	u.Lock()
+	defer u.Unlock()
+
+	u.expire()
+	if u.pending == nil {
+		u.pending = make(map[string]*upload)
+	}
+
+	u.pending[token] = &upload{
+		Token:      token,
+		Statements: stmts,
+		expires:    time.Now().Add(uploadTTL),
+	}
+
+	return token, nil
+}
+
+func (u *uploads) get(token string) (*upload, bool) {
+	u.Lock()
+	defer u.Unlock()
+
+	u.expire()
+	up, ok := u.pending[token]
+	return up, ok
+}
+
+func (u *uploads) remove(token string) {
+	u.Lock()
+	defer u.Unlock()
+
+	delete(u.pending, token)
+}
+
+func (u *uploads) expire() {
+	now := time.Now()
+	for token, up := range u.pending {
+		if now.After(up.expires) {
+			delete(u.pending, token)
+		}
+	}
+}